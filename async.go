@@ -2,11 +2,11 @@ package treblle
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"golang.org/x/sync/semaphore"
 )
 
 // contextKey type for request context values
@@ -19,49 +19,162 @@ const (
 	treblleRequestInfoKey contextKey = "treblle_request_info"
 )
 
-// AsyncProcessor manages asynchronous processing with controlled concurrency
+// OverflowPolicy decides what happens to an event when the async queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest discards the event being enqueued
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest discards the oldest queued event to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowBlock waits up to AsyncEnqueueTimeout for room before discarding the event
+	OverflowBlock OverflowPolicy = "block"
+)
+
+const (
+	// defaultAsyncQueueSize is the queue capacity when AsyncQueueSize is not set
+	defaultAsyncQueueSize = 1000
+	// defaultAsyncEnqueueTimeout is how long OverflowBlock waits when AsyncEnqueueTimeout is not set
+	defaultAsyncEnqueueTimeout = 100 * time.Millisecond
+)
+
+// asyncJob is a captured event waiting to be sent
+type asyncJob struct {
+	meta   MetaData
+	target eventTarget
+}
+
+// ProcessorStats counts the events handled by the async processor and the routes collapsed by
+// the cardinality guard
+type ProcessorStats struct {
+	Enqueued int64 `json:"enqueued"` // Events accepted into the queue
+	Sent     int64 `json:"sent"`     // Events delivered to Treblle
+	Dropped  int64 `json:"dropped"`  // Events discarded because the queue was full or closed
+	Failed   int64 `json:"failed"`   // Events that could not be delivered
+	Queued   int   `json:"queued"`   // Events currently waiting in the queue
+
+	// Route prefixes collapsed by RouteCardinalityLimit since the last Configure (Stats only)
+	RoutesCollapsed int64 `json:"routes_collapsed"`
+}
+
+// AsyncProcessor sends events to Treblle from a fixed pool of workers fed by a bounded queue
 type AsyncProcessor struct {
-	sem    *semaphore.Weighted
-	wg     sync.WaitGroup
-	ctx    context.Context
-	cancel context.CancelFunc
+	queue          chan asyncJob
+	policy         OverflowPolicy
+	enqueueTimeout time.Duration
+
+	mu     sync.RWMutex // Guards closed and sending on queue
+	closed bool
+
+	wg      sync.WaitGroup // Events accepted but not yet processed
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	enqueued atomic.Int64
+	sent     atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+	inFlight atomic.Int64 // Events being delivered by a worker
 }
 
 // RequestTracker stores and retrieves request data using context
 type RequestTracker struct{}
 
 var (
-	// Global async processor instance
-	asyncProcessor     *AsyncProcessor
-	asyncProcessorOnce sync.Once
+	// Global async processor instance, replaced by Configure after Shutdown
+	asyncProcessor   *AsyncProcessor
+	asyncProcessorMu sync.Mutex
 
 	// Global request tracker instance
 	requestTracker     *RequestTracker
 	requestTrackerOnce sync.Once
 )
 
-// NewAsyncProcessor creates a new async processor with controlled concurrency
+// NewAsyncProcessor creates a new async processor with maxConcurrent workers. The queue size
+// and overflow policy are taken from the configuration.
 func NewAsyncProcessor(maxConcurrent int64) *AsyncProcessor {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 10
+	}
+
+	queueSize := Config.AsyncQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultAsyncQueueSize
+	}
+
+	policy := Config.AsyncOverflowPolicy
+	if policy == "" {
+		policy = OverflowDropNewest
+	}
+
+	enqueueTimeout := Config.AsyncEnqueueTimeout
+	if enqueueTimeout <= 0 {
+		enqueueTimeout = defaultAsyncEnqueueTimeout
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	return &AsyncProcessor{
-		sem:    semaphore.NewWeighted(maxConcurrent),
-		ctx:    ctx,
-		cancel: cancel,
+	ap := &AsyncProcessor{
+		queue:          make(chan asyncJob, queueSize),
+		policy:         policy,
+		enqueueTimeout: enqueueTimeout,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	for i := int64(0); i < maxConcurrent; i++ {
+		ap.workers.Add(1)
+		go ap.work()
 	}
+	return ap
 }
 
 // GetAsyncProcessor returns the singleton async processor
 func GetAsyncProcessor() *AsyncProcessor {
-	asyncProcessorOnce.Do(func() {
+	asyncProcessorMu.Lock()
+	defer asyncProcessorMu.Unlock()
+
+	if asyncProcessor == nil {
 		maxConcurrent := 10 // Default value
 		if Config.MaxConcurrentProcessing > 0 {
 			maxConcurrent = Config.MaxConcurrentProcessing
 		}
 		asyncProcessor = NewAsyncProcessor(int64(maxConcurrent))
-	})
+	}
 	return asyncProcessor
 }
 
+// currentAsyncProcessor returns the async processor without starting one
+func currentAsyncProcessor() *AsyncProcessor {
+	asyncProcessorMu.Lock()
+	defer asyncProcessorMu.Unlock()
+	return asyncProcessor
+}
+
+// resetAsyncProcessor discards a processor that has been shut down so the next
+// GetAsyncProcessor starts a new one
+func resetAsyncProcessor() {
+	asyncProcessorMu.Lock()
+	defer asyncProcessorMu.Unlock()
+
+	if asyncProcessor != nil && asyncProcessor.isClosed() {
+		asyncProcessor = nil
+	}
+}
+
+// Stats returns the counters of the async processor, or zero counters if none has been started,
+// along with the number of routes collapsed by the cardinality guard
+func Stats() ProcessorStats {
+	var stats ProcessorStats
+	if processor := currentAsyncProcessor(); processor != nil {
+		stats = processor.Stats()
+	}
+	if guard := Config.routeCardinalityGuard; guard != nil {
+		stats.RoutesCollapsed = guard.collapseCount()
+	}
+	return stats
+}
+
 // GetRequestTracker returns the singleton request tracker
 func GetRequestTracker() *RequestTracker {
 	requestTrackerOnce.Do(func() {
@@ -70,48 +183,129 @@ func GetRequestTracker() *RequestTracker {
 	return requestTracker
 }
 
-// Process handles the asynchronous processing of Treblle data
+// Process queues Treblle data to be sent by a worker. The event is built from the current
+// configuration immediately. If the queue is full, the configured overflow policy decides which
+// event is dropped.
 func (ap *AsyncProcessor) Process(requestInfo RequestInfo, responseInfo ResponseInfo, errorProvider *ErrorProvider) {
+	ap.enqueue(newMetaData(Config.serverInfo, requestInfo, responseInfo), currentEventTarget())
+}
+
+// enqueue queues a complete event to be sent by a worker
+func (ap *AsyncProcessor) enqueue(meta MetaData, target eventTarget) {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+
+	if ap.closed {
+		ap.drop("processor is shut down")
+		return
+	}
+
+	job := asyncJob{meta: meta, target: target}
 	ap.wg.Add(1)
 
-	// Process asynchronously
-	go func() {
-		defer ap.wg.Done()
+	select {
+	case ap.queue <- job:
+		ap.enqueued.Add(1)
+		return
+	default:
+	}
 
-		// Create a context with timeout for acquiring the semaphore
-		acquireCtx, cancel := context.WithTimeout(ap.ctx, 100*time.Millisecond)
-		defer cancel()
+	switch ap.policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case ap.queue <- job:
+				ap.enqueued.Add(1)
+				return
+			default:
+			}
 
-		// Try to acquire the semaphore
-		if err := ap.sem.Acquire(acquireCtx, 1); err != nil {
-			// If we can't acquire the semaphore in time, just drop the request
-			// This prevents backpressure during high load
-			return
+			// Make room by discarding the oldest queued event
+			select {
+			case <-ap.queue:
+				ap.drop("queue full, dropped oldest event")
+				ap.wg.Done()
+			default:
+			}
 		}
-		defer ap.sem.Release(1)
-
-		// Create metadata
-		ti := MetaData{
-			ApiKey:    Config.APIKey,
-			ProjectID: Config.ProjectID,
-			Version:   Config.SDKVersion,
-			Sdk:       Config.SDKName,
-			//	Url:       requestInfo.RoutePath, // Use the normalized URL from requestInfo (critical for endpoint grouping)
-			Data: DataInfo{
-				Server:   Config.serverInfo,
-				Language: Config.languageInfo,
-				Request:  requestInfo,
-				Response: responseInfo,
-			},
+	case OverflowBlock:
+		timer := time.NewTimer(ap.enqueueTimeout)
+		defer timer.Stop()
+
+		select {
+		case ap.queue <- job:
+			ap.enqueued.Add(1)
+			return
+		case <-timer.C:
 		}
+	}
+
+	ap.drop("queue full, dropped newest event")
+	ap.wg.Done()
+}
 
-		// Use a context with timeout for the API call
-		sendCtx, sendCancel := context.WithTimeout(ap.ctx, 2*time.Second)
-		defer sendCancel()
+// work sends queued events until the queue is closed
+func (ap *AsyncProcessor) work() {
+	defer ap.workers.Done()
 
-		// Send to Treblle with context
-		sendToTreblleWithContext(sendCtx, ti)
+	for job := range ap.queue {
+		// Events left in the queue after a shutdown deadline are discarded
+		if ap.ctx.Err() != nil {
+			ap.drop("processor shut down before the event was sent")
+			ap.wg.Done()
+			continue
+		}
+		ap.send(job)
+	}
+}
+
+// send delivers a single queued event to Treblle
+func (ap *AsyncProcessor) send(job asyncJob) {
+	ap.inFlight.Add(1)
+	defer ap.inFlight.Add(-1)
+	defer ap.wg.Done()
+	defer func() {
+		if err := recover(); err != nil {
+			ap.failed.Add(1)
+		}
 	}()
+
+	// Use a context with timeout for the API call
+	sendCtx, sendCancel := context.WithTimeout(ap.ctx, 2*time.Second)
+	defer sendCancel()
+
+	// Send to the exporters and Treblle with context
+	if err := deliver(sendCtx, job.meta, job.target); err != nil {
+		ap.failed.Add(1)
+		if debugEnabled() {
+			fmt.Printf("==== DEBUG: TREBLLE ASYNC SEND FAILED ====\n")
+			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("================================\n")
+		}
+		return
+	}
+	ap.sent.Add(1)
+}
+
+// drop counts a discarded event
+func (ap *AsyncProcessor) drop(reason string) {
+	ap.dropped.Add(1)
+	if debugEnabled() {
+		fmt.Printf("==== DEBUG: TREBLLE EVENT DROPPED ====\n")
+		fmt.Printf("Reason: %s\n", reason)
+		fmt.Printf("================================\n")
+	}
+}
+
+// Stats returns the counters of the processor
+func (ap *AsyncProcessor) Stats() ProcessorStats {
+	return ProcessorStats{
+		Enqueued: ap.enqueued.Load(),
+		Sent:     ap.sent.Load(),
+		Dropped:  ap.dropped.Load(),
+		Failed:   ap.failed.Load(),
+		Queued:   len(ap.queue),
+	}
 }
 
 // Wait waits for all processing to complete with a timeout
@@ -132,11 +326,56 @@ func (ap *AsyncProcessor) Wait(timeout time.Duration) bool {
 
 // Shutdown gracefully shuts down the processor
 func (ap *AsyncProcessor) Shutdown(timeout time.Duration) {
-	// Signal cancellation to all operations
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ap.drain(ctx)
+}
+
+// drain stops accepting events and waits for queued ones to be sent until ctx is done. Events
+// still queued or in flight at that point are abandoned and returned as lost.
+func (ap *AsyncProcessor) drain(ctx context.Context) (int64, error) {
+	ap.mu.Lock()
+	if !ap.closed {
+		ap.closed = true
+		close(ap.queue)
+	}
+	ap.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ap.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		ap.cancel()
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	// Abort in-flight sends; the workers discard whatever is still queued. Sends that ignore
+	// the cancellation are abandoned after a grace period so the caller's deadline holds.
+	before := ap.dropped.Load() + ap.failed.Load()
 	ap.cancel()
 
-	// Wait for ongoing operations to complete
-	ap.Wait(timeout)
+	grace := time.NewTimer(drainGracePeriod)
+	defer grace.Stop()
+
+	select {
+	case <-done:
+		return ap.dropped.Load() + ap.failed.Load() - before, ctx.Err()
+	case <-grace.C:
+		abandoned := ap.inFlight.Load() + int64(len(ap.queue))
+		return ap.dropped.Load() + ap.failed.Load() - before + abandoned, ctx.Err()
+	}
+}
+
+// isClosed reports whether the processor has been shut down
+func (ap *AsyncProcessor) isClosed() bool {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+	return ap.closed
 }
 
 // StoreStartTime stores the request start time in context
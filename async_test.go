@@ -1,26 +1,27 @@
 package treblle
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAsyncProcessor_Process(t *testing.T) {
 	// Setup test configuration
-	originalConfig := Config
-	defer func() {
-		Config = originalConfig
-	}()
-
-	Config = internalConfiguration{
+	useConfig(t, internalConfiguration{
 		AsyncProcessingEnabled:  true,
 		MaxConcurrentProcessing: 2,
 		AsyncShutdownTimeout:    1 * time.Second,
 		SDKName:                 "treblle-go-test",
 		SDKVersion:              0.1,
-	}
+	})
 
 	// Create a mock request and response
 	req, err := http.NewRequest("GET", "/test", nil)
@@ -46,7 +47,8 @@ func TestAsyncProcessor_Process(t *testing.T) {
 	errorProvider := NewErrorProvider()
 
 	// Create a new async processor
-	processor := GetAsyncProcessor()
+	processor := NewAsyncProcessor(int64(Config.MaxConcurrentProcessing))
+	useTestProcessor(t, processor)
 
 	// Test processing multiple requests
 	var wg sync.WaitGroup
@@ -109,21 +111,17 @@ func TestRequestTracker(t *testing.T) {
 
 func TestAsyncShutdown(t *testing.T) {
 	// Setup test configuration
-	originalConfig := Config
-	defer func() {
-		Config = originalConfig
-	}()
-
-	Config = internalConfiguration{
+	useConfig(t, internalConfiguration{
 		AsyncProcessingEnabled:  true,
 		MaxConcurrentProcessing: 2,
 		AsyncShutdownTimeout:    500 * time.Millisecond,
 		SDKName:                 "treblle-go-test",
 		SDKVersion:              0.1,
-	}
+	})
 
 	// Create a new async processor
-	processor := GetAsyncProcessor()
+	processor := NewAsyncProcessor(int64(Config.MaxConcurrentProcessing))
+	useTestProcessor(t, processor)
 
 	// Skip the semaphore test as it's an implementation detail
 	// Just test the shutdown timeout
@@ -136,3 +134,181 @@ func TestAsyncShutdown(t *testing.T) {
 		t.Errorf("Shutdown took %v, expected to be quick", duration)
 	}
 }
+
+func TestStatsDoesNotStartProcessor(t *testing.T) {
+	useTestProcessor(t, nil)
+
+	assert.Equal(t, ProcessorStats{}, Stats())
+	assert.Nil(t, currentAsyncProcessor(), "reading stats must not start a worker pool")
+
+	processor := NewAsyncProcessor(1)
+	useTestProcessor(t, processor)
+	processor.dropped.Add(1)
+	assert.Equal(t, int64(1), Stats().Dropped)
+}
+
+// drainSends waits for the events sent by the test when it ends, so they are neither delivered
+// to the collectors of later tests nor still in flight when the configuration changes
+func drainSends(t *testing.T) {
+	t.Cleanup(waitForSends)
+}
+
+// waitForSends waits for synchronous sends and the events queued in the async processor
+func waitForSends() {
+	syncSends.wait()
+	if processor := currentAsyncProcessor(); processor != nil {
+		processor.Wait(2 * time.Second)
+	}
+}
+
+// useTestProcessor installs processor as the global async processor for the test and shuts
+// down the one it replaces. When the test ends, processor is shut down and removed too.
+func useTestProcessor(t *testing.T, processor *AsyncProcessor) {
+	replaceAsyncProcessor(processor)
+	t.Cleanup(func() {
+		replaceAsyncProcessor(nil)
+		resetShutdown()
+	})
+}
+
+// replaceAsyncProcessor swaps the global async processor and shuts down the previous one
+func replaceAsyncProcessor(processor *AsyncProcessor) {
+	asyncProcessorMu.Lock()
+	previous := asyncProcessor
+	asyncProcessor = processor
+	asyncProcessorMu.Unlock()
+
+	if previous != nil && previous != processor {
+		previous.Shutdown(time.Second)
+	}
+}
+
+// blockingCollector is a mock Treblle endpoint that holds every request until released
+type blockingCollector struct {
+	server   *httptest.Server
+	received chan string
+	release  chan struct{}
+}
+
+func newBlockingCollector(t *testing.T, status int) *blockingCollector {
+	c := &blockingCollector{
+		received: make(chan string, 100),
+		release:  make(chan struct{}),
+	}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err != nil {
+			return
+		}
+		c.received <- meta.Data.Request.Url
+		<-c.release
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+// newQueueTestProcessor returns a single-worker processor whose worker is busy with /queue/0
+func newQueueTestProcessor(t *testing.T, collector *blockingCollector, policy OverflowPolicy) *AsyncProcessor {
+	useConfig(t, internalConfiguration{
+		Endpoint:            collector.server.URL,
+		AsyncQueueSize:      2,
+		AsyncOverflowPolicy: policy,
+		AsyncEnqueueTimeout: 50 * time.Millisecond,
+	})
+
+	processor := NewAsyncProcessor(1)
+	processor.Process(RequestInfo{Url: "/queue/0"}, ResponseInfo{}, nil)
+
+	select {
+	case <-collector.received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker did not pick up the first event")
+	}
+	return processor
+}
+
+func TestAsyncProcessorDropNewest(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusOK)
+	processor := newQueueTestProcessor(t, collector, OverflowDropNewest)
+
+	for i := 1; i <= 3; i++ {
+		processor.Process(RequestInfo{Url: fmt.Sprintf("/queue/%d", i)}, ResponseInfo{}, nil)
+	}
+
+	stats := processor.Stats()
+	assert.Equal(t, int64(3), stats.Enqueued)
+	assert.Equal(t, int64(1), stats.Dropped)
+	assert.Equal(t, 2, stats.Queued)
+
+	close(collector.release)
+	assert.Equal(t, "/queue/1", <-collector.received)
+	assert.Equal(t, "/queue/2", <-collector.received)
+
+	processor.Shutdown(2 * time.Second)
+	stats = processor.Stats()
+	assert.Equal(t, int64(3), stats.Sent)
+	assert.Equal(t, int64(0), stats.Failed)
+	assert.Equal(t, 0, stats.Queued)
+}
+
+func TestAsyncProcessorDropOldest(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusOK)
+	processor := newQueueTestProcessor(t, collector, OverflowDropOldest)
+
+	for i := 1; i <= 3; i++ {
+		processor.Process(RequestInfo{Url: fmt.Sprintf("/queue/%d", i)}, ResponseInfo{}, nil)
+	}
+
+	stats := processor.Stats()
+	assert.Equal(t, int64(4), stats.Enqueued)
+	assert.Equal(t, int64(1), stats.Dropped)
+
+	close(collector.release)
+	assert.Equal(t, "/queue/2", <-collector.received)
+	assert.Equal(t, "/queue/3", <-collector.received)
+
+	processor.Shutdown(2 * time.Second)
+	assert.Equal(t, int64(3), processor.Stats().Sent)
+}
+
+func TestAsyncProcessorBlockWithTimeout(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusOK)
+	processor := newQueueTestProcessor(t, collector, OverflowBlock)
+
+	processor.Process(RequestInfo{Url: "/queue/1"}, ResponseInfo{}, nil)
+	processor.Process(RequestInfo{Url: "/queue/2"}, ResponseInfo{}, nil)
+
+	start := time.Now()
+	processor.Process(RequestInfo{Url: "/queue/3"}, ResponseInfo{}, nil)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "Process should wait for room before dropping")
+	assert.Equal(t, int64(1), processor.Stats().Dropped)
+
+	// Room freed while blocking lets the event in
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		collector.release <- struct{}{}
+	}()
+	processor.Process(RequestInfo{Url: "/queue/4"}, ResponseInfo{}, nil)
+	assert.Equal(t, int64(1), processor.Stats().Dropped)
+	assert.Equal(t, int64(4), processor.Stats().Enqueued)
+
+	close(collector.release)
+	processor.Shutdown(2 * time.Second)
+}
+
+func TestAsyncProcessorCountsFailuresAndRejectsAfterShutdown(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusInternalServerError)
+	processor := newQueueTestProcessor(t, collector, OverflowDropNewest)
+
+	close(collector.release)
+	processor.Shutdown(2 * time.Second)
+
+	processor.Process(RequestInfo{Url: "/queue/1"}, ResponseInfo{}, nil)
+
+	stats := processor.Stats()
+	require.Equal(t, int64(1), stats.Enqueued)
+	assert.Equal(t, int64(0), stats.Sent)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(1), stats.Dropped)
+}
@@ -5,12 +5,18 @@ import (
 	"time"
 )
 
-// BatchErrorCollector handles batch collection and transmission of errors
+// BatchErrorCollector handles batch collection and transmission of errors.
+// Errors with the same fingerprint are aggregated into a single entry per batch.
 type BatchErrorCollector struct {
 	mu            sync.Mutex
 	errors        []ErrorInfo
+	fingerprints  map[string]int // fingerprint -> index in errors
 	batchSize     int
 	flushInterval time.Duration
+	rateLimit     int           // Maximum times a fingerprint is sent per rateWindow (0 = unlimited)
+	rateWindow    time.Duration // Window for rateLimit
+	sent          map[string][]time.Time
+	now           func() time.Time
 	done          chan struct{}
 	wg            sync.WaitGroup
 }
@@ -26,8 +32,11 @@ func NewBatchErrorCollector(batchSize int, flushInterval time.Duration) *BatchEr
 
 	collector := &BatchErrorCollector{
 		errors:        make([]ErrorInfo, 0, batchSize),
+		fingerprints:  make(map[string]int),
 		batchSize:     batchSize,
 		flushInterval: flushInterval,
+		sent:          make(map[string][]time.Time),
+		now:           time.Now,
 		done:          make(chan struct{}),
 	}
 
@@ -35,29 +44,87 @@ func NewBatchErrorCollector(batchSize int, flushInterval time.Duration) *BatchEr
 	return collector
 }
 
-// Add adds an error to the batch
+// SetRateLimit limits how often each fingerprint is sent to Treblle. Occurrences beyond the
+// limit keep being counted and are sent once the window allows it. A limit of 0 disables it.
+func (b *BatchErrorCollector) SetRateLimit(limit int, window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if window <= 0 {
+		window = time.Minute
+	}
+	b.rateLimit = limit
+	b.rateWindow = window
+}
+
+// Add adds an error to the batch, aggregating it with earlier occurrences of the same fingerprint
 func (b *BatchErrorCollector) Add(err ErrorInfo) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	now := b.now().UTC().Format("2006-01-02 15:04:05")
+	occurrences := err.Occurrences
+	if occurrences <= 0 {
+		occurrences = 1
+	}
+
+	fingerprint := fingerprintError(err)
+	if index, ok := b.fingerprints[fingerprint]; ok {
+		b.errors[index].Occurrences += occurrences
+		b.errors[index].LastSeen = now
+		return
+	}
+
+	err.Fingerprint = fingerprint
+	err.Occurrences = occurrences
+	if err.FirstSeen == "" {
+		err.FirstSeen = now
+	}
+	err.LastSeen = now
+
+	b.fingerprints[fingerprint] = len(b.errors)
 	b.errors = append(b.errors, err)
 	if len(b.errors) >= b.batchSize {
-		b.flush()
+		b.flush(false)
 	}
 }
 
-// flush sends the current batch of errors to Treblle
-func (b *BatchErrorCollector) flush() {
+// flush sends the current batch of errors to Treblle. Unless force is set, errors whose
+// fingerprint is over the rate limit are kept for a later batch.
+func (b *BatchErrorCollector) flush(force bool) {
 	if len(b.errors) == 0 {
 		return
 	}
 
-	// Create a copy of errors to send
-	errorsCopy := make([]ErrorInfo, len(b.errors))
-	copy(errorsCopy, b.errors)
+	// Split the batch into errors to send now and rate-limited ones to keep aggregating
+	errorsCopy := make([]ErrorInfo, 0, len(b.errors))
+	var held []ErrorInfo
+	for _, err := range b.errors {
+		if force || b.allow(err.Fingerprint) {
+			errorsCopy = append(errorsCopy, err)
+		} else {
+			held = append(held, err)
+		}
+	}
 
-	// Clear the current batch
+	// Clear the current batch, keeping held errors
 	b.errors = b.errors[:0]
+	b.fingerprints = make(map[string]int, len(held))
+	for _, err := range held {
+		b.fingerprints[err.Fingerprint] = len(b.errors)
+		b.errors = append(b.errors, err)
+	}
+
+	// Forget fingerprints that have not been sent within the window
+	for fingerprint, sentAt := range b.sent {
+		if len(sentAt) > 0 && b.now().Sub(sentAt[len(sentAt)-1]) >= b.rateWindow {
+			delete(b.sent, fingerprint)
+		}
+	}
+
+	if len(errorsCopy) == 0 {
+		return
+	}
 
 	// Send errors asynchronously
 	b.wg.Add(1)
@@ -79,10 +146,32 @@ func (b *BatchErrorCollector) flush() {
 		}
 
 		// Send to Treblle
-		sendToTreblle(meta)
+		sendToTreblle(meta, currentEventTarget())
 	}(errorsCopy)
 }
 
+// allow records a send for the fingerprint if it is within the rate limit
+func (b *BatchErrorCollector) allow(fingerprint string) bool {
+	if b.rateLimit <= 0 {
+		return true
+	}
+
+	now := b.now()
+	recent := b.sent[fingerprint][:0]
+	for _, sentAt := range b.sent[fingerprint] {
+		if now.Sub(sentAt) < b.rateWindow {
+			recent = append(recent, sentAt)
+		}
+	}
+
+	if len(recent) >= b.rateLimit {
+		b.sent[fingerprint] = recent
+		return false
+	}
+	b.sent[fingerprint] = append(recent, now)
+	return true
+}
+
 // periodicFlush periodically flushes the error batch based on the flush interval
 func (b *BatchErrorCollector) periodicFlush() {
 	ticker := time.NewTicker(b.flushInterval)
@@ -92,7 +181,7 @@ func (b *BatchErrorCollector) periodicFlush() {
 		select {
 		case <-ticker.C:
 			b.mu.Lock()
-			b.flush()
+			b.flush(false)
 			b.mu.Unlock()
 		case <-b.done:
 			return
@@ -111,14 +200,24 @@ func (b *BatchErrorCollector) Close() {
 		return
 	default:
 		close(b.done)
-		b.flush()
+		b.flush(true)
 		b.wg.Wait()
 	}
 }
 
+// isClosed reports whether Close has been called
+func (b *BatchErrorCollector) isClosed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
 // Flush sends any pending errors to Treblle immediately
 func (b *BatchErrorCollector) Flush() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.flush()
+	b.flush(true)
 }
@@ -81,3 +81,74 @@ func TestBatchErrorCollector(t *testing.T) {
 		collector.mu.Unlock()
 	})
 }
+
+func TestBatchErrorCollectorAggregation(t *testing.T) {
+	collector := NewBatchErrorCollector(100, time.Hour)
+	defer collector.Close()
+
+	for _, id := range []string{"42", "43", "1007"} {
+		collector.Add(ErrorInfo{
+			Message: "user " + id + " not found",
+			Type:    NotFoundError,
+			Source:  "users",
+			File:    "users.go",
+			Line:    10,
+		})
+	}
+	collector.Add(ErrorInfo{Message: "user 44 not found", Type: NotFoundError, Source: "users", File: "users.go", Line: 11})
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	assert.Len(t, collector.errors, 2, "Errors at the same location with the same normalized message should be aggregated")
+	assert.Equal(t, 3, collector.errors[0].Occurrences)
+	assert.Equal(t, "user 42 not found", collector.errors[0].Message, "The first message should be kept")
+	assert.NotEmpty(t, collector.errors[0].Fingerprint)
+	assert.NotEmpty(t, collector.errors[0].FirstSeen)
+	assert.NotEmpty(t, collector.errors[0].LastSeen)
+	assert.Equal(t, 1, collector.errors[1].Occurrences)
+}
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	assert.Equal(t, "user {n} not found", normalizeErrorMessage("user 42 not found"))
+	assert.Equal(t, "order {uuid} failed", normalizeErrorMessage("order 550e8400-e29b-41d4-a716-446655440000 failed"))
+	assert.Equal(t, "object {hex} missing", normalizeErrorMessage("object 507f1f77bcf86cd799439011 missing"))
+	assert.Equal(t, "unknown field {str}", normalizeErrorMessage(`unknown field "email"`))
+	assert.Equal(t, "timeout after {n}s", normalizeErrorMessage("timeout after 1.5s"))
+	assert.Equal(t, "deadbeef", normalizeErrorMessage("deadbeef"))
+}
+
+func TestBatchErrorCollectorRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	collector := NewBatchErrorCollector(100, time.Hour)
+	collector.now = func() time.Time { return now }
+	collector.SetRateLimit(1, time.Minute)
+	defer collector.Close()
+
+	failure := ErrorInfo{Message: "dependency down", Type: ServerError, Source: "db", File: "db.go", Line: 1}
+
+	// First flush sends the fingerprint
+	collector.Add(failure)
+	collector.mu.Lock()
+	collector.flush(false)
+	assert.Empty(t, collector.errors)
+	collector.mu.Unlock()
+
+	// Within the window it is held back and keeps counting
+	collector.Add(failure)
+	collector.Add(failure)
+	collector.mu.Lock()
+	collector.flush(false)
+	assert.Len(t, collector.errors, 1)
+	assert.Equal(t, 2, collector.errors[0].Occurrences)
+	collector.mu.Unlock()
+
+	// Once the window has passed it is sent with the accumulated count
+	now = now.Add(time.Minute)
+	collector.Add(failure)
+	collector.mu.Lock()
+	assert.Equal(t, 3, collector.errors[0].Occurrences)
+	collector.flush(false)
+	assert.Empty(t, collector.errors)
+	collector.mu.Unlock()
+}
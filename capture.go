@@ -0,0 +1,173 @@
+package treblle
+
+import (
+	"context"
+	"errors"
+	"runtime"
+)
+
+// treblleCaptureTagsKey is the key for storing tags attached with ContextWithTags
+const treblleCaptureTagsKey contextKey = "treblle_capture_tags"
+
+// CaptureOption customises an error captured with CaptureError or CaptureMessage
+type CaptureOption func(*ErrorInfo)
+
+// WithErrorType sets the error type (default: ServerError)
+func WithErrorType(errType ErrorType) CaptureOption {
+	return func(info *ErrorInfo) {
+		info.Type = errType
+	}
+}
+
+// WithSource sets the error source (default: "capture")
+func WithSource(source string) CaptureOption {
+	return func(info *ErrorInfo) {
+		info.Source = source
+	}
+}
+
+// WithTag adds a single tag to the captured error
+func WithTag(key, value string) CaptureOption {
+	return func(info *ErrorInfo) {
+		if info.Tags == nil {
+			info.Tags = make(map[string]string)
+		}
+		info.Tags[key] = value
+	}
+}
+
+// WithTags adds tags to the captured error
+func WithTags(tags map[string]string) CaptureOption {
+	return func(info *ErrorInfo) {
+		for key, value := range tags {
+			WithTag(key, value)(info)
+		}
+	}
+}
+
+// WithExtra attaches arbitrary context, such as a job ID or payload summary, to the captured error
+func WithExtra(key string, value interface{}) CaptureOption {
+	return func(info *ErrorInfo) {
+		if info.Extra == nil {
+			info.Extra = make(map[string]interface{})
+		}
+		info.Extra[key] = value
+	}
+}
+
+// ContextWithTags returns a context whose tags are added to every error captured with it.
+// Tags already on ctx are kept unless overridden.
+//
+// Example:
+//
+//	ctx = treblle.ContextWithTags(ctx, map[string]string{"job": "send-invoices"})
+func ContextWithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	for key, value := range contextTags(ctx) {
+		merged[key] = value
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, treblleCaptureTagsKey, merged)
+}
+
+// contextTags returns the tags stored on ctx by ContextWithTags
+func contextTags(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(treblleCaptureTagsKey).(map[string]string)
+	return tags
+}
+
+// CaptureError sends an error that did not happen during an HTTP request, such as one from a
+// background job, queue consumer or cron task, to Treblle through the batch error collector.
+// The file and line are those of the CaptureError call. It is a no-op unless BatchErrorEnabled is set,
+// and after Shutdown until the next Configure.
+//
+// Example:
+//
+//	if err := job.Run(ctx); err != nil {
+//		treblle.CaptureError(ctx, err, treblle.WithTag("queue", "emails"))
+//	}
+func CaptureError(ctx context.Context, err error, opts ...CaptureOption) {
+	if err == nil {
+		return
+	}
+
+	info := newCapturedError(ctx, err.Error(), opts)
+	if info == nil {
+		return
+	}
+
+	var withContext *ErrorWithContext
+	if errors.As(err, &withContext) && info.Source == "capture" {
+		if source := errorContextSource(withContext.Context); source != "" {
+			info.Source = source
+		}
+	}
+	addErrorDetails(info, err, 1)
+
+	Config.batchErrorCollector.Add(*info)
+}
+
+// CaptureMessage sends a message to Treblle through the batch error collector, like CaptureError.
+// It is a no-op unless BatchErrorEnabled is set.
+func CaptureMessage(ctx context.Context, message string, opts ...CaptureOption) {
+	info := newCapturedError(ctx, message, opts)
+	if info == nil {
+		return
+	}
+
+	Config.batchErrorCollector.Add(*info)
+}
+
+// newCapturedError builds the ErrorInfo for a capture call, attributed to the caller of the
+// exported function. It returns nil when captured errors would be dropped, including after
+// Shutdown has closed the collector.
+func newCapturedError(ctx context.Context, message string, opts []CaptureOption) *ErrorInfo {
+	if Config.batchErrorCollector == nil || isShutdown() || IsEnvironmentIgnored() {
+		return nil
+	}
+
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		file = "unknown"
+		line = 0
+	}
+
+	info := &ErrorInfo{
+		Message: message,
+		Type:    ServerError,
+		File:    cleanFilePath(file),
+		Line:    line,
+		Source:  "capture",
+	}
+
+	WithTags(contextTags(ctx))(info)
+	for _, opt := range opts {
+		opt(info)
+	}
+	return info
+}
+
+// mirrorRequestErrors copies the errors of a captured request into the batch error collector
+// when BatchErrorMirrorRequests is set, tagged with the request's method and route
+func mirrorRequestErrors(requestInfo RequestInfo, errs []ErrorInfo) {
+	if !Config.MirrorRequestErrors || Config.batchErrorCollector == nil || isShutdown() {
+		return
+	}
+
+	for _, info := range errs {
+		tags := map[string]string{
+			"method": requestInfo.Method,
+			"route":  requestInfo.RoutePath,
+		}
+		for key, value := range info.Tags {
+			tags[key] = value
+		}
+		info.Tags = tags
+		Config.batchErrorCollector.Add(info)
+	}
+}
@@ -0,0 +1,106 @@
+package treblle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// CapturedResponse describes a response produced outside of Middleware
+type CapturedResponse struct {
+	StatusCode    int            // Response status; 0 means 200 OK, as with net/http
+	Header        http.Header    // Response headers
+	Body          []byte         // Response body
+	StartTime     time.Time      // When handling of the request started
+	Duration      time.Duration  // Time taken to produce the response (default: time since StartTime)
+	ErrorProvider *ErrorProvider // Errors to attach (default: the provider in the context, if any)
+}
+
+// Capture sends a request/response pair to Treblle for code paths that cannot use Middleware,
+// such as Lambda handlers or custom servers. The event goes through the same masking, route
+// normalization, GraphQL and JSON-RPC grouping, sampling, ignored routes and (a)synchronous
+// delivery as requests captured by Middleware.
+//
+// If StartTime is zero, the start time stored by the request tracker is used, then
+// time.Now() minus Duration.
+//
+// Example:
+//
+//	start := time.Now()
+//	body, status := handle(req)
+//	treblle.Capture(ctx, req, treblle.CapturedResponse{
+//		StatusCode: status,
+//		Header:     http.Header{"Content-Type": {"application/json"}},
+//		Body:       body,
+//		StartTime:  start,
+//	})
+func Capture(ctx context.Context, req *http.Request, resp CapturedResponse) {
+	if req == nil || IsEnvironmentIgnored() || !shouldCapture(req) {
+		return
+	}
+	if ctx == nil {
+		ctx = req.Context()
+	}
+
+	errorProvider := resp.ErrorProvider
+	if errorProvider == nil {
+		errorProvider = FromContext(ctx)
+	}
+	if errorProvider == nil {
+		errorProvider = NewErrorProvider()
+	}
+
+	startTime, duration := captureTiming(req, resp)
+
+	requestInfo, errReqInfo := getRequestInfo(req, startTime, errorProvider)
+	if errReqInfo != nil && !errors.Is(errReqInfo, ErrNotJson) {
+		errorProvider.AddError(errReqInfo, ValidationError, "request_processing")
+	}
+	requestInfo.Timestamp = startTime.UTC().Format("2006-01-02 15:04:05")
+
+	// Group GraphQL traffic by operation rather than by the single HTTP endpoint
+	graphQL := isGraphQLRequest(req)
+	if graphQL {
+		requestInfo.RoutePath = getGraphQLRoutePath(req, requestInfo.RoutePath, requestInfo.Body)
+	}
+
+	// Reuse the middleware's response handling by replaying the response into a recorder
+	code := resp.StatusCode
+	if code == 0 {
+		code = http.StatusOK
+	}
+	rec := httptest.NewRecorder()
+	for k, v := range resp.Header {
+		rec.Header()[k] = v
+	}
+	rec.Code = code
+	rec.Body.Write(resp.Body)
+
+	responseInfo := getResponseInfo(rec, startTime, errorProvider)
+	responseInfo.LoadTime = float64(duration.Microseconds()) / 1000.0
+
+	serverInfo := Config.serverInfo
+	serverInfo.Protocol = DetectProtocol(req)
+
+	dispatchResponse(req, rec, graphQL, serverInfo, requestInfo, responseInfo, errorProvider)
+}
+
+// captureTiming resolves the start time and duration of a manually captured request
+func captureTiming(req *http.Request, resp CapturedResponse) (time.Time, time.Duration) {
+	startTime := resp.StartTime
+	if startTime.IsZero() {
+		if stored, ok := GetRequestTracker().GetStartTime(req); ok {
+			startTime = stored
+		} else {
+			startTime = time.Now().Add(-resp.Duration)
+		}
+	}
+
+	duration := resp.Duration
+	if duration <= 0 {
+		duration = time.Since(startTime)
+	}
+	return startTime, duration
+}
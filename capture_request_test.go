@@ -0,0 +1,170 @@
+package treblle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureEvents starts a mock Treblle endpoint that keeps the events sent by the test
+func captureEvents(t *testing.T) chan MetaData {
+	received := make(chan MetaData, 10)
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil {
+			received <- meta
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(treblleServer.Close)
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	Configure(Configuration{
+		SDK_TOKEN:           "test-sdk-token",
+		API_KEY:             "test-api-key",
+		Endpoint:            treblleServer.URL,
+		DefaultFieldsToMask: []string{"password"},
+	})
+	return received
+}
+
+func TestCapture(t *testing.T) {
+	received := captureEvents(t)
+
+	errorProvider := NewErrorProvider()
+	req := httptest.NewRequest(http.MethodPost, "/lambda/42", strings.NewReader(`{"password":"secret"}`))
+	req = withErrorProvider(req, errorProvider)
+	req.Header.Set("Content-Type", "application/json")
+	ReportError(req.Context(), errors.New("quota exceeded"), RateLimitError)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	Capture(req.Context(), req, CapturedResponse{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"error":"slow down"}`),
+		StartTime:  start,
+		Duration:   250 * time.Millisecond,
+	})
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, "/lambda/{id}", meta.Data.Request.RoutePath)
+		assert.Equal(t, "2024-03-01 12:00:00", meta.Data.Request.Timestamp)
+		assert.JSONEq(t, `{"password":"*********"}`, string(meta.Data.Request.Body))
+		assert.Equal(t, http.StatusTooManyRequests, meta.Data.Response.Code)
+		assert.Equal(t, 250.0, meta.Data.Response.LoadTime)
+		assert.Equal(t, "HTTP/1.1", meta.Data.Server.Protocol)
+		require.Len(t, meta.Data.Response.Errors, 1)
+		assert.Equal(t, "quota exceeded", meta.Data.Response.Errors[0].Message)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestCaptureDefaults(t *testing.T) {
+	received := captureEvents(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/cron/cleanup", nil)
+	req = GetRequestTracker().StoreStartTime(req)
+	Capture(req.Context(), req, CapturedResponse{Body: []byte("done")})
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, http.StatusOK, meta.Data.Response.Code, "a zero status code means 200 OK")
+		assert.JSONEq(t, `"done"`, string(meta.Data.Response.Body))
+		assert.GreaterOrEqual(t, meta.Data.Response.LoadTime, 0.0)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestCaptureSkipsIgnoredRoutes(t *testing.T) {
+	received := captureEvents(t)
+	require.NoError(t, UpdateSettings(Settings{IgnoredRoutes: []string{"/cron/*"}}))
+
+	for _, path := range []string{"/cron/cleanup", "/lambda/7"} {
+		Capture(context.Background(), httptest.NewRequest(http.MethodGet, path, nil), CapturedResponse{})
+	}
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, "/lambda/{id}", meta.Data.Request.RoutePath)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+	waitForSends()
+	assert.Empty(t, received, "ignored routes are not captured")
+}
+
+func TestCaptureGroupsProtocols(t *testing.T) {
+	received := captureEvents(t)
+	Config.GraphQLEnabled = true
+	Config.JSONRPCEnabled = true
+
+	graphQL := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query GetUser { user { id } }"}`))
+	Capture(context.Background(), graphQL, CapturedResponse{
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`{"data":null,"errors":[{"message":"user not found","extensions":{"code":"NOT_FOUND"}}]}`),
+	})
+
+	jsonRPC := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`[{"jsonrpc":"2.0","method":"math.add","id":1},{"jsonrpc":"2.0","method":"math.sub","id":2}]`))
+	Capture(context.Background(), jsonRPC, CapturedResponse{
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`[{"jsonrpc":"2.0","result":1,"id":1},{"jsonrpc":"2.0","result":2,"id":2}]`),
+	})
+
+	events := make(map[string]MetaData)
+	for len(events) < 3 {
+		select {
+		case meta := <-received:
+			events[meta.Data.Request.RoutePath] = meta
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d of 3 events", len(events))
+		}
+	}
+
+	require.Contains(t, events, "/graphql/query/GetUser")
+	errs := events["/graphql/query/GetUser"].Data.Response.Errors
+	require.Len(t, errs, 1)
+	assert.Equal(t, NotFoundError, errs[0].Type)
+	assert.Contains(t, events, "/rpc/math.add")
+	assert.Contains(t, events, "/rpc/math.sub")
+}
+
+func TestShutdownRequestKeepsSharedState(t *testing.T) {
+	received := captureEvents(t)
+	collector := NewBatchErrorCollector(10, time.Hour)
+	Config.batchErrorCollector = collector
+	defer func() {
+		collector.Close()
+		Config.batchErrorCollector = nil
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/legacy/report", nil)
+	w := httptest.NewRecorder()
+	w.WriteHeader(http.StatusAccepted)
+	ShutdownRequest(req, w, nil, nil)
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, http.StatusAccepted, meta.Data.Response.Code)
+		assert.Equal(t, Config.SDKVersion, meta.Version)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	select {
+	case <-collector.done:
+		t.Fatal("the batch error collector should not be closed")
+	default:
+	}
+}
@@ -0,0 +1,139 @@
+package treblle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestCollector installs a batch error collector that never flushes on its own
+func useTestCollector(t *testing.T) *BatchErrorCollector {
+	previous := Config.batchErrorCollector
+	collector := NewBatchErrorCollector(100, time.Hour)
+	Config.batchErrorCollector = collector
+	t.Cleanup(func() {
+		collector.mu.Lock()
+		collector.errors = nil
+		collector.fingerprints = make(map[string]int)
+		collector.mu.Unlock()
+		collector.Close()
+		Config.batchErrorCollector = previous
+	})
+	return collector
+}
+
+// collectedErrors returns the errors pending in the collector
+func collectedErrors(collector *BatchErrorCollector) []ErrorInfo {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	return append([]ErrorInfo(nil), collector.errors...)
+}
+
+func TestCaptureErrorWithoutCollector(t *testing.T) {
+	previous := Config.batchErrorCollector
+	Config.batchErrorCollector = nil
+	defer func() { Config.batchErrorCollector = previous }()
+
+	// Must not panic when batch errors are disabled
+	CaptureError(context.Background(), errors.New("ignored"))
+	CaptureMessage(context.Background(), "ignored")
+}
+
+func TestCaptureError(t *testing.T) {
+	collector := useTestCollector(t)
+
+	ctx := ContextWithTags(context.Background(), map[string]string{"job": "send-invoices", "queue": "default"})
+	CaptureError(ctx, errors.New("smtp unavailable"),
+		WithErrorType(ValidationError),
+		WithTag("queue", "emails"),
+		WithExtra("attempt", 3),
+	)
+	_, _, line, _ := runtime.Caller(0)
+
+	errs := collectedErrors(collector)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "smtp unavailable", errs[0].Message)
+	assert.Equal(t, ValidationError, errs[0].Type)
+	assert.Equal(t, "capture", errs[0].Source)
+	assert.True(t, strings.HasSuffix(errs[0].File, "capture_test.go"), errs[0].File)
+	assert.Equal(t, line-5, errs[0].Line)
+	assert.Equal(t, map[string]string{"job": "send-invoices", "queue": "emails"}, errs[0].Tags)
+	assert.Equal(t, map[string]interface{}{"attempt": 3}, errs[0].Extra)
+	assert.Equal(t, 1, errs[0].Occurrences)
+}
+
+func TestCaptureErrorWithContext(t *testing.T) {
+	collector := useTestCollector(t)
+
+	CaptureError(context.Background(), saveUser())
+
+	errs := collectedErrors(collector)
+	require.Len(t, errs, 1)
+	assert.Equal(t, ServerError, errs[0].Type)
+	assert.Equal(t, "github.com/Treblle/treblle-go/v2.saveUser", errs[0].Source)
+}
+
+func TestCaptureMessage(t *testing.T) {
+	collector := useTestCollector(t)
+
+	for i := 0; i < 3; i++ {
+		CaptureMessage(context.Background(), "cron run skipped", WithSource("cron"))
+	}
+
+	errs := collectedErrors(collector)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "cron run skipped", errs[0].Message)
+	assert.Equal(t, "cron", errs[0].Source)
+	assert.Equal(t, 3, errs[0].Occurrences)
+}
+
+func TestMirrorRequestErrors(t *testing.T) {
+	collector := useTestCollector(t)
+
+	previous := Config.MirrorRequestErrors
+	Config.MirrorRequestErrors = true
+	defer func() { Config.MirrorRequestErrors = previous }()
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReportError(r.Context(), errors.New("db timeout"), ServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	errs := collectedErrors(collector)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "db timeout", errs[0].Message)
+	assert.Equal(t, "handler", errs[0].Source)
+	assert.Equal(t, "GET", errs[0].Tags["method"])
+	assert.Equal(t, "/users/{id}", errs[0].Tags["route"])
+}
+
+func TestCaptureErrorAfterShutdown(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{BatchErrorEnabled: true, DisableTreblle: true})
+	collector := Config.batchErrorCollector
+	require.NotNil(t, collector)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, Shutdown(ctx))
+
+	// The closed collector would never flush again
+	CaptureError(context.Background(), errors.New("after shutdown"))
+	assert.Empty(t, collectedErrors(collector))
+
+	// Configuring without batching drops the closed collector
+	Configure(Configuration{DisableTreblle: true})
+	assert.Nil(t, Config.batchErrorCollector)
+}
@@ -0,0 +1,110 @@
+package treblle
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collapsedSegmentPlaceholder replaces segments whose prefix exceeded the cardinality limit
+const collapsedSegmentPlaceholder = "{param}"
+
+// routeCardinalityGuard limits the number of distinct values seen after each route prefix.
+// Once a prefix exceeds the limit, the segment that follows it is collapsed to a placeholder
+// for all future requests. Prefixes are tracked in a bounded LRU so memory stays flat.
+type routeCardinalityGuard struct {
+	mu          sync.Mutex
+	limit       int
+	maxPrefixes int
+	prefixes    map[string]*list.Element
+	lru         *list.List
+	collapses   atomic.Int64
+}
+
+// routePrefixEntry holds the distinct segment values seen after a prefix
+type routePrefixEntry struct {
+	prefix    string
+	values    map[string]struct{}
+	collapsed bool
+}
+
+// newRouteCardinalityGuard creates a guard that collapses a segment once more than limit
+// distinct values have been seen after the same prefix
+func newRouteCardinalityGuard(limit, maxPrefixes int) *routeCardinalityGuard {
+	if maxPrefixes <= 0 {
+		maxPrefixes = 1000
+	}
+	return &routeCardinalityGuard{
+		limit:       limit,
+		maxPrefixes: maxPrefixes,
+		prefixes:    make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// apply records the path segments and collapses those whose prefix is over the limit. Top-level
+// segments are not counted, as they are the static resources of the API rather than values.
+func (g *routeCardinalityGuard) apply(segments []string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for i := 2; i < len(segments); i++ {
+		segment := segments[i]
+		if segment == "" || isPlaceholderSegment(segment) {
+			continue
+		}
+
+		prefix := strings.Join(segments[:i], "/")
+		entry := g.entry(prefix)
+		if entry.collapsed {
+			segments[i] = collapsedSegmentPlaceholder
+			continue
+		}
+
+		entry.values[segment] = struct{}{}
+		if len(entry.values) > g.limit {
+			entry.collapsed = true
+			entry.values = nil
+			g.collapses.Add(1)
+			segments[i] = collapsedSegmentPlaceholder
+
+			if debugEnabled() {
+				fmt.Printf("==== DEBUG: TREBLLE ROUTE CARDINALITY ====\n")
+				fmt.Printf("More than %d distinct segments after %q, collapsing to %s\n", g.limit, prefix+"/", collapsedSegmentPlaceholder)
+				fmt.Printf("================================\n")
+			}
+		}
+	}
+	return segments
+}
+
+// entry returns the tracked entry for prefix, creating it and evicting the least recently
+// used prefix if needed
+func (g *routeCardinalityGuard) entry(prefix string) *routePrefixEntry {
+	if elem, ok := g.prefixes[prefix]; ok {
+		g.lru.MoveToFront(elem)
+		return elem.Value.(*routePrefixEntry)
+	}
+
+	if g.lru.Len() >= g.maxPrefixes {
+		oldest := g.lru.Back()
+		g.lru.Remove(oldest)
+		delete(g.prefixes, oldest.Value.(*routePrefixEntry).prefix)
+	}
+
+	entry := &routePrefixEntry{prefix: prefix, values: make(map[string]struct{})}
+	g.prefixes[prefix] = g.lru.PushFront(entry)
+	return entry
+}
+
+// collapseCount returns how many prefixes have been collapsed since the guard was created
+func (g *routeCardinalityGuard) collapseCount() int64 {
+	return g.collapses.Load()
+}
+
+// isPlaceholderSegment checks if a segment is already a {placeholder}
+func isPlaceholderSegment(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
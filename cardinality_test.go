@@ -0,0 +1,65 @@
+package treblle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteCardinalityGuard(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{RouteCardinalityLimit: 3})
+
+	for i := 0; i < 3; i++ {
+		path := fmt.Sprintf("/files/name-%c.txt", 'a'+i)
+		assert.Equal(t, path, normalizeRoutePath(path))
+	}
+
+	// The fourth distinct value collapses the segment from now on
+	assert.Equal(t, "/files/{param}", normalizeRoutePath("/files/name-d.txt"))
+	assert.Equal(t, "/files/{param}/download", normalizeRoutePath("/files/name-e.txt/download"))
+	assert.Equal(t, int64(1), Stats().RoutesCollapsed)
+
+	// Placeholders produced by the route rules are not counted
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "/users/{id}", normalizeRoutePath(fmt.Sprintf("/users/%d", i)))
+	}
+	assert.Equal(t, int64(1), Stats().RoutesCollapsed)
+
+	// Reconfiguring starts a new guard
+	Configure(Configuration{RouteCardinalityLimit: 3})
+	assert.Equal(t, int64(0), Stats().RoutesCollapsed)
+}
+
+func TestRouteCardinalityGuardKeepsTopLevelRoutes(t *testing.T) {
+	guard := newRouteCardinalityGuard(1, 0)
+
+	for _, path := range []string{"/users", "/orders", "/health", "/users/me"} {
+		assert.Equal(t, path, strings.Join(guard.apply(strings.Split(path, "/")), "/"))
+	}
+	assert.Equal(t, int64(0), guard.collapseCount())
+}
+
+func TestRouteCardinalityGuardEviction(t *testing.T) {
+	guard := newRouteCardinalityGuard(10, 2)
+
+	guard.apply([]string{"", "a", "x"})
+	guard.apply([]string{"", "b", "x"})
+	assert.Len(t, guard.prefixes, 2)
+
+	// Tracking a third prefix evicts the least recently used one
+	guard.apply([]string{"", "c", "x"})
+	assert.Len(t, guard.prefixes, 2)
+	assert.NotContains(t, guard.prefixes, "/a")
+	assert.Contains(t, guard.prefixes, "/c")
+}
+
+func TestRouteCardinalityGuardDisabled(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{})
+	assert.Nil(t, Config.routeCardinalityGuard)
+}
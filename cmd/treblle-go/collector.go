@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCollectorBodySize limits the decompressed size of an ingest request
+const maxCollectorBodySize = 32 * 1024 * 1024
+
+// collectorOptions configures the mock collector
+type collectorOptions struct {
+	apiKey       string        // Expected x-api-key; any non-empty key is accepted if empty
+	out          io.Writer     // Receives one JSON event per line, if set
+	print        io.Writer     // Receives pretty-printed events, if set
+	latency      time.Duration // Added before every response
+	errorRate    float64       // Fraction of requests answered with errorStatus
+	errorStatus  int           // Status for simulated failures
+	throttleRate float64       // Fraction of requests answered with 429
+	retryAfter   time.Duration // Retry-After sent with 429 responses
+	random       func() float64
+}
+
+// collector is a mock Treblle ingest endpoint
+type collector struct {
+	opts     collectorOptions
+	mu       sync.Mutex // Serializes output
+	accepted int
+}
+
+// runCollector implements `treblle-go collector`
+func runCollector(args []string) int {
+	fs := flag.NewFlagSet("collector", flag.ContinueOnError)
+	listen := fs.String("listen", ":8787", "Address to listen on")
+	apiKey := fs.String("api-key", "", "Expected x-api-key header (default: accept any non-empty key)")
+	outPath := fs.String("out", "", "Append received events to this JSONL file")
+	quiet := fs.Bool("quiet", false, "Do not pretty-print received events")
+	latency := fs.Duration("latency", 0, "Delay added to every response")
+	errorRate := fs.Float64("error-rate", 0, "Fraction of requests (0-1) answered with -error-status")
+	errorStatus := fs.Int("error-status", http.StatusInternalServerError, "Status code for simulated failures")
+	throttleRate := fs.Float64("throttle-rate", 0, "Fraction of requests (0-1) answered with 429 Too Many Requests")
+	retryAfter := fs.Duration("retry-after", time.Second, "Retry-After sent with 429 responses")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go collector [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	opts := collectorOptions{
+		apiKey:       *apiKey,
+		latency:      *latency,
+		errorRate:    *errorRate,
+		errorStatus:  *errorStatus,
+		throttleRate: *throttleRate,
+		retryAfter:   *retryAfter,
+	}
+	if !*quiet {
+		opts.print = os.Stdout
+	}
+	if *outPath != "" {
+		file, err := os.OpenFile(*outPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		opts.out = file
+	}
+
+	log.Printf("Treblle mock collector listening on %s", *listen)
+	log.Printf("Point the SDK at it with Endpoint: \"http://%s\"", collectorDisplayAddr(*listen))
+	if err := http.ListenAndServe(*listen, newCollector(opts)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// newCollector returns the ingest handler
+func newCollector(opts collectorOptions) *collector {
+	if opts.random == nil {
+		opts.random = rand.Float64
+	}
+	if opts.errorStatus == 0 {
+		opts.errorStatus = http.StatusInternalServerError
+	}
+	return &collector{opts: opts}
+}
+
+// ServeHTTP implements http.Handler
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeCollectorResponse(w, http.StatusMethodNotAllowed, "only POST is accepted", nil)
+		return
+	}
+
+	key := r.Header.Get("x-api-key")
+	if key == "" || (c.opts.apiKey != "" && key != c.opts.apiKey) {
+		log.Printf("rejected request with x-api-key %q", key)
+		writeCollectorResponse(w, http.StatusUnauthorized, "invalid x-api-key", nil)
+		return
+	}
+
+	if c.opts.latency > 0 {
+		select {
+		case <-time.After(c.opts.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if c.opts.throttleRate > 0 && c.opts.random() < c.opts.throttleRate {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(c.opts.retryAfter.Seconds()))))
+		writeCollectorResponse(w, http.StatusTooManyRequests, "simulated rate limit", nil)
+		return
+	}
+	if c.opts.errorRate > 0 && c.opts.random() < c.opts.errorRate {
+		writeCollectorResponse(w, c.opts.errorStatus, "simulated failure", nil)
+		return
+	}
+
+	body, err := readCollectorBody(r)
+	if err != nil {
+		writeCollectorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	events, err := splitEvents(body)
+	if err != nil {
+		writeCollectorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	var problems []string
+	for i, raw := range events {
+		var event interface{}
+		if err := json.Unmarshal(raw, &event); err != nil {
+			problems = append(problems, fmt.Sprintf("event %d: %v", i, err))
+			continue
+		}
+		for _, problem := range checkEvent(event) {
+			problems = append(problems, fmt.Sprintf("event %d: %s", i, problem))
+		}
+	}
+	if len(problems) > 0 {
+		log.Printf("rejected %d events:\n  %s", len(events), strings.Join(problems, "\n  "))
+		writeCollectorResponse(w, http.StatusBadRequest, "schema validation failed", problems)
+		return
+	}
+
+	c.record(events)
+	writeCollectorResponse(w, http.StatusOK, fmt.Sprintf("accepted %d events", len(events)), nil)
+}
+
+// record writes accepted events to the configured outputs
+func (c *collector) record(events []json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, raw := range events {
+		c.accepted++
+		if c.opts.out != nil {
+			var line bytes.Buffer
+			if err := json.Compact(&line, raw); err == nil {
+				line.WriteByte('\n')
+				c.opts.out.Write(line.Bytes())
+			}
+		}
+		if c.opts.print != nil {
+			var pretty bytes.Buffer
+			if err := json.Indent(&pretty, raw, "", "  "); err == nil {
+				fmt.Fprintf(c.opts.print, "==== EVENT #%d ====\n%s\n", c.accepted, pretty.String())
+			}
+		}
+	}
+}
+
+// readCollectorBody reads the request body, decompressing gzip and deflate encodings
+func readCollectorBody(r *http.Request) ([]byte, error) {
+	var reader io.Reader = r.Body
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		zr, err := zlib.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate body: %w", err)
+		}
+		defer zr.Close()
+		reader = zr
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxCollectorBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(body) > maxCollectorBodySize {
+		return nil, fmt.Errorf("body is larger than %d bytes", maxCollectorBodySize)
+	}
+	return body, nil
+}
+
+// splitEvents splits a single event, a JSON array of events or concatenated/JSONL events
+func splitEvents(body []byte) ([]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("empty body")
+	}
+
+	if body[0] == '[' {
+		var events []json.RawMessage
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		return events, nil
+	}
+
+	var events []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for decoder.More() {
+		var event json.RawMessage
+		if err := decoder.Decode(&event); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// collectorResponse is the body of every collector response
+type collectorResponse struct {
+	Status  int      `json:"status"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// writeCollectorResponse writes a JSON status message
+func writeCollectorResponse(w http.ResponseWriter, status int, message string, problems []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(collectorResponse{Status: status, Message: message, Errors: problems})
+}
+
+// collectorDisplayAddr turns a listen address such as ":8787" into a dialable host:port
+func collectorDisplayAddr(listen string) string {
+	if strings.HasPrefix(listen, ":") {
+		return "localhost" + listen
+	}
+	return listen
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Treblle/treblle-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validEvent returns a complete event as the SDK sends it
+func validEvent(t *testing.T) []byte {
+	event := treblle.MetaData{
+		ApiKey:    "sdk-token",
+		ProjectID: "api-key",
+		Version:   2.0,
+		Sdk:       "go",
+		Data: treblle.DataInfo{
+			Request:  treblle.RequestInfo{Method: "GET", Url: "http://localhost/users"},
+			Response: treblle.ResponseInfo{Code: 200},
+		},
+	}
+	body, err := json.Marshal(event)
+	require.NoError(t, err)
+	return body
+}
+
+func postToCollector(c *collector, body []byte, header http.Header) (*httptest.ResponseRecorder, collectorResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("x-api-key", "sdk-token")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+
+	var resp collectorResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestCollectorAcceptsEvents(t *testing.T) {
+	var out, printed bytes.Buffer
+	c := newCollector(collectorOptions{apiKey: "sdk-token", out: &out, print: &printed})
+
+	event := validEvent(t)
+	rec, _ := postToCollector(c, event, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Batched as an array and as JSONL
+	rec, resp := postToCollector(c, []byte("["+string(event)+","+string(event)+"]"), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "accepted 2 events", resp.Message)
+	rec, _ = postToCollector(c, []byte(string(event)+"\n"+string(event)+"\n"), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Compressed
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(event)
+	gz.Close()
+	rec, _ = postToCollector(c, compressed.Bytes(), http.Header{"Content-Encoding": {"gzip"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, 6, strings.Count(out.String(), "\n"), "one JSONL line per event")
+	assert.Contains(t, printed.String(), "==== EVENT #6 ====")
+}
+
+func TestCollectorValidatesRequests(t *testing.T) {
+	c := newCollector(collectorOptions{apiKey: "sdk-token"})
+
+	rec, _ := postToCollector(c, validEvent(t), http.Header{"X-Api-Key": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, _ = postToCollector(c, validEvent(t), http.Header{"Content-Encoding": {"br"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, resp := postToCollector(c, []byte(`{"api_key":"sdk-token","version":"2","data":{"request":{"method":1}}}`), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, resp.Errors, "event 0: project_id: missing")
+	assert.Contains(t, resp.Errors, "event 0: version: expected number, got string")
+	assert.Contains(t, resp.Errors, "event 0: data.request.method: expected string, got number")
+	assert.Contains(t, resp.Errors, "event 0: data.server: missing")
+}
+
+func TestCollectorSimulatesFailures(t *testing.T) {
+	always := func() float64 { return 0 }
+
+	c := newCollector(collectorOptions{throttleRate: 1, retryAfter: 1500 * time.Millisecond, random: always})
+	rec, _ := postToCollector(c, validEvent(t), nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	c = newCollector(collectorOptions{errorRate: 1, errorStatus: http.StatusBadGateway, random: always})
+	rec, _ = postToCollector(c, validEvent(t), nil)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+
+	c = newCollector(collectorOptions{latency: 50 * time.Millisecond})
+	start := time.Now()
+	rec, _ = postToCollector(c, validEvent(t), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestCollectorAcceptsSDKPayload(t *testing.T) {
+	body, err := os.ReadFile("../../testdata/event.golden.json")
+	require.NoError(t, err)
+
+	var event interface{}
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Empty(t, checkEvent(event))
+}
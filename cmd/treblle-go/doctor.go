@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// doctorOptions configures the checks run by `treblle-go doctor`
+type doctorOptions struct {
+	timeout   time.Duration // Timeout for each network check
+	offline   bool          // Skip DNS, TLS and the test event
+	noSend    bool          // Skip the test event
+	tlsConfig *tls.Config   // Used for the TLS handshake and the test event, if set
+}
+
+// runDoctor implements `treblle-go doctor`
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	configFile := fs.String("config", "", "Config file, as passed to Configuration.ConfigFile (default: $TREBLLE_CONFIG)")
+	sdkToken := fs.String("sdk-token", "", "SDK token, as passed to Configuration.SDK_TOKEN")
+	apiKey := fs.String("api-key", "", "API key, as passed to Configuration.API_KEY")
+	endpoint := fs.String("endpoint", "", "Custom endpoint, as passed to Configuration.Endpoint")
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout for each network check")
+	offline := fs.Bool("offline", false, "Only check the configuration and environment")
+	noSend := fs.Bool("no-send", false, "Check DNS and TLS but do not send a test event")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go doctor [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	config := treblle.Configuration{
+		SDK_TOKEN:  *sdkToken,
+		API_KEY:    *apiKey,
+		Endpoint:   *endpoint,
+		ConfigFile: *configFile,
+	}
+	opts := doctorOptions{timeout: *timeout, offline: *offline, noSend: *noSend}
+	return doctor(os.Stdout, config, opts)
+}
+
+// doctor configures the SDK like an application would, reports the resolved configuration and
+// checks that events can reach Treblle. It returns 1 if any check failed.
+func doctor(w io.Writer, config treblle.Configuration, opts doctorOptions) int {
+	failed := false
+	fail := func(format string, args ...interface{}) {
+		failed = true
+		fmt.Fprintf(w, "✗ "+format+"\n", args...)
+	}
+
+	fmt.Fprintln(w, "Configuration")
+	described := map[string]treblle.ConfigValue{}
+	for _, value := range treblle.DescribeConfiguration(config) {
+		described[value.Name] = value
+		fmt.Fprintf(w, "  %-24s %-40s %s\n", value.Name, displayValue(value.Value), describeSource(value))
+	}
+	treblle.Configure(config)
+
+	fmt.Fprintln(w, "\nChecks")
+	fileLoaded := true
+	var configErr *treblle.ConfigError
+	if errors.As(config.Validate(), &configErr) {
+		for _, problem := range configErr.Problems {
+			fileLoaded = fileLoaded && problem.Setting != "ConfigFile"
+			fail("%s", problem)
+		}
+	} else {
+		fmt.Fprintf(w, "✓ the configuration is valid\n")
+	}
+	if path := described["ConfigFile"].Value; path != "" && fileLoaded {
+		fmt.Fprintf(w, "✓ config file %s loaded\n", path)
+	}
+	for _, warning := range treblle.EnvironmentWarnings() {
+		fmt.Fprintf(w, "! %s\n", warning)
+	}
+	if treblle.IsEnvironmentIgnored() {
+		fmt.Fprintf(w, "! the current environment is ignored; requests are not captured\n")
+	} else {
+		fmt.Fprintf(w, "✓ the current environment is not ignored\n")
+	}
+
+	if opts.offline {
+		return exitStatus(failed)
+	}
+
+	for _, endpoint := range treblle.Endpoints() {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			fail("%s: invalid endpoint", endpoint)
+			continue
+		}
+
+		addrs, elapsed, err := lookupHost(u.Hostname(), opts.timeout)
+		if err != nil {
+			fail("%s: DNS lookup failed: %v", u.Hostname(), err)
+			continue
+		}
+		fmt.Fprintf(w, "✓ %s: resolved to %v in %s\n", u.Hostname(), addrs, elapsed.Round(time.Millisecond))
+
+		if u.Scheme == "https" {
+			state, elapsed, err := checkTLS(hostPort(u), opts.timeout, opts.tlsConfig)
+			if err != nil {
+				fail("%s: TLS handshake failed: %v", u.Host, err)
+				continue
+			}
+			fmt.Fprintf(w, "✓ %s: %s handshake in %s, certificate valid until %s\n", u.Host, tls.VersionName(state.Version),
+				elapsed.Round(time.Millisecond), state.PeerCertificates[0].NotAfter.UTC().Format("2006-01-02"))
+		} else {
+			fmt.Fprintf(w, "! %s: not using TLS\n", u.Host)
+		}
+
+		if opts.noSend {
+			continue
+		}
+		status, elapsed, err := sendTestEvent(endpoint, opts.timeout, opts.tlsConfig)
+		switch {
+		case err != nil:
+			fail("%s: test event failed: %v", endpoint, err)
+		case status >= 400:
+			fail("%s: test event rejected with %d %s in %s", endpoint, status, http.StatusText(status), elapsed.Round(time.Millisecond))
+		default:
+			fmt.Fprintf(w, "✓ %s: test event accepted with %d %s in %s\n", endpoint, status, http.StatusText(status), elapsed.Round(time.Millisecond))
+		}
+	}
+
+	return exitStatus(failed)
+}
+
+// describeSource explains where a configuration value came from; code values are passed as flags
+func describeSource(value treblle.ConfigValue) string {
+	switch value.Source {
+	case treblle.SourceCode:
+		return "(flag)"
+	case treblle.SourceEnv:
+		return fmt.Sprintf("(env %s)", value.EnvVar)
+	default:
+		return fmt.Sprintf("(%s)", value.Source)
+	}
+}
+
+// displayValue shows empty values explicitly
+func displayValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// exitStatus returns 1 if a check failed
+func exitStatus(failed bool) int {
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// hostPort returns the address to dial for u
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// lookupHost resolves host and reports how long it took
+func lookupHost(host string, timeout time.Duration) ([]string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	return addrs, time.Since(start), err
+}
+
+// checkTLS completes a TLS handshake with addr and returns the connection state
+func checkTLS(addr string, timeout time.Duration, config *tls.Config) (tls.ConnectionState, time.Duration, error) {
+	if config == nil {
+		config = &tls.Config{}
+	}
+
+	start := time.Now()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, config)
+	if err != nil {
+		return tls.ConnectionState{}, time.Since(start), err
+	}
+	defer conn.Close()
+	return conn.ConnectionState(), time.Since(start), nil
+}
+
+// sendTestEvent posts a test event, authenticated with the configured SDK token, to endpoint
+func sendTestEvent(endpoint string, timeout time.Duration, config *tls.Config) (int, time.Duration, error) {
+	body, err := json.Marshal(doctorEvent())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", treblle.Config.APIKey)
+
+	client := &http.Client{Timeout: timeout}
+	if config != nil {
+		client.Transport = &http.Transport{TLSClientConfig: config}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, time.Since(start), err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, time.Since(start), nil
+}
+
+// doctorEvent builds the test event sent by doctor
+func doctorEvent() treblle.MetaData {
+	empty := json.RawMessage("{}")
+	return treblle.MetaData{
+		ApiKey:    treblle.Config.APIKey,
+		ProjectID: treblle.Config.ProjectID,
+		Version:   treblle.Config.SDKVersion,
+		Sdk:       treblle.Config.SDKName,
+		Data: treblle.DataInfo{
+			Server:   treblle.GetServerInfo(nil),
+			Language: treblle.GetLanguageInfo(),
+			Request: treblle.RequestInfo{
+				Timestamp: time.Now().UTC().Format("2006-01-02 15:04:05"),
+				Ip:        "127.0.0.1",
+				Url:       "/treblle-go/doctor",
+				RoutePath: "/treblle-go/doctor",
+				UserAgent: "treblle-go doctor",
+				Method:    http.MethodGet,
+				Headers:   empty,
+				Body:      empty,
+				Query:     empty,
+			},
+			Response: treblle.ResponseInfo{
+				Headers: empty,
+				Code:    http.StatusOK,
+				Body:    empty,
+				Errors:  []treblle.ErrorInfo{},
+			},
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Treblle/treblle-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useDoctorConfig restores the SDK configuration changed by doctor
+func useDoctorConfig(t *testing.T) {
+	original := treblle.Config
+	t.Cleanup(func() { treblle.Config = original })
+	for _, name := range []string{"GO_ENV", "ENV", "ENVIRONMENT", "APP_ENV", "TREBLLE_IGNORED_ENV", "TREBLLE_SDK_NAME",
+		"TREBLLE_CONFIG", "TREBLLE_SDK_TOKEN", "TREBLLE_API_KEY", "TREBLLE_ENDPOINT"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestDoctorSendsTestEvent(t *testing.T) {
+	useDoctorConfig(t)
+	t.Setenv("TREBLLE_SDK_NAME", "go-doctor")
+
+	c := newCollector(collectorOptions{apiKey: "sdk-token"})
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	var out bytes.Buffer
+	config := treblle.Configuration{SDK_TOKEN: "sdk-token", API_KEY: "api-key", Endpoint: server.URL}
+	code := doctor(&out, config, doctorOptions{timeout: time.Second})
+
+	assert.Equal(t, 0, code, out.String())
+	assert.Equal(t, 1, c.accepted)
+	assert.Regexp(t, `SDKName\s+go-doctor\s+\(env TREBLLE_SDK_NAME\)`, out.String())
+	assert.Regexp(t, `Endpoint\s+http://127\.0\.0\.1:\d+\s+\(flag\)`, out.String())
+	assert.Contains(t, out.String(), "✓ the current environment is not ignored")
+	assert.Contains(t, out.String(), "test event accepted with 200 OK")
+}
+
+func TestDoctorReportsRejectedEvent(t *testing.T) {
+	useDoctorConfig(t)
+
+	server := httptest.NewServer(newCollector(collectorOptions{apiKey: "other-token"}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	config := treblle.Configuration{SDK_TOKEN: "sdk-token", API_KEY: "api-key", Endpoint: server.URL}
+	code := doctor(&out, config, doctorOptions{timeout: time.Second})
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "test event rejected with 401 Unauthorized")
+}
+
+func TestDoctorReportsEnvironment(t *testing.T) {
+	useDoctorConfig(t)
+	t.Setenv("GO_ENV", "staging")
+	t.Setenv("TREBLLE_IGNORED_ENV", "staging")
+	t.Setenv("TREBLLE_IGNORED_ENVIRONMENTS", "production")
+	t.Setenv("TREBLLE_SDK_VERSION", "two")
+
+	var out bytes.Buffer
+	code := doctor(&out, treblle.Configuration{}, doctorOptions{offline: true})
+
+	assert.Equal(t, 1, code, "missing SDK_TOKEN and API_KEY fail")
+	assert.Contains(t, out.String(), "✗ SDK_TOKEN is not set")
+	assert.Contains(t, out.String(), `✗ TREBLLE_SDK_VERSION is not a number (got "two")`)
+	assert.Regexp(t, `IgnoredEnvironments\s+staging\s+\(env TREBLLE_IGNORED_ENV\)`, out.String())
+	assert.Contains(t, out.String(), "! TREBLLE_IGNORED_ENVIRONMENTS is not read by Configure; use TREBLLE_IGNORED_ENV")
+	assert.Contains(t, out.String(), "! the current environment is ignored")
+}
+
+func TestDoctorChecksTLS(t *testing.T) {
+	useDoctorConfig(t)
+
+	server := httptest.NewTLSServer(newCollector(collectorOptions{}))
+	defer server.Close()
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	var out bytes.Buffer
+	config := treblle.Configuration{SDK_TOKEN: "sdk-token", API_KEY: "api-key", Endpoint: server.URL}
+	code := doctor(&out, config, doctorOptions{timeout: time.Second, noSend: true, tlsConfig: tlsConfig})
+	assert.Equal(t, 0, code, out.String())
+	assert.Contains(t, out.String(), tls.VersionName(tls.VersionTLS13)+" handshake")
+
+	// The test server's certificate is not trusted by default
+	out.Reset()
+	code = doctor(&out, config, doctorOptions{timeout: time.Second, noSend: true})
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "TLS handshake failed")
+}
+
+func TestDoctorReportsConfigFile(t *testing.T) {
+	useDoctorConfig(t)
+
+	path := filepath.Join(t.TempDir(), "treblle.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("sdk_token: file-sdk-token\napi_key: file-api-key\n"), 0o644))
+
+	var out bytes.Buffer
+	code := doctor(&out, treblle.Configuration{ConfigFile: path}, doctorOptions{offline: true})
+
+	assert.Equal(t, 0, code, out.String())
+	assert.Regexp(t, `SDK_TOKEN\s+\*+oken\s+\(file\)`, out.String())
+	assert.Contains(t, out.String(), "✓ config file "+path+" loaded")
+
+	require.NoError(t, os.WriteFile(path, []byte("sdk_tokn: typo\n"), 0o644))
+	out.Reset()
+	code = doctor(&out, treblle.Configuration{ConfigFile: path}, doctorOptions{offline: true})
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "✗ ConfigFile cannot be loaded: "+path)
+	assert.Contains(t, out.String(), "sdk_tokn")
+}
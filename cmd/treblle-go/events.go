@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// maxEventLineSize is the longest JSONL line accepted when reading events
+const maxEventLineSize = 16 * 1024 * 1024
+
+// loadEvents reads Treblle events from each file. A file may hold a single JSON event, a JSON
+// array of events or one event per line (JSONL).
+func loadEvents(paths []string) ([]treblle.MetaData, error) {
+	var events []treblle.MetaData
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		fileEvents, err := readEvents(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, fileEvents...)
+	}
+	return events, nil
+}
+
+// readEvents decodes events from r, detecting JSON, JSON array and JSONL input
+func readEvents(r io.Reader) ([]treblle.MetaData, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if data[0] == '[' {
+		var events []treblle.MetaData
+		if err := json.Unmarshal(data, &events); err != nil {
+			return nil, err
+		}
+		return events, nil
+	}
+
+	// A single, possibly pretty-printed, event
+	var event treblle.MetaData
+	if json.Valid(data) {
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		return []treblle.MetaData{event}, nil
+	}
+
+	var events []treblle.MetaData
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var event treblle.MetaData
+		if err := json.Unmarshal(text, &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
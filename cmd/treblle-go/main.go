@@ -1,13 +1,42 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/Treblle/treblle-go/v2"
 )
 
+// command is a treblle-go subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands lists the available subcommands in the order they are shown in the usage
+var commands = []command{
+	{"replay", "Replay captured Treblle events against a server", runReplay},
+	{"collector", "Run a local mock Treblle collector", runCollector},
+	{"doctor", "Check the SDK configuration and connectivity to Treblle", runDoctor},
+	{"mask", "Show how masking rules apply to a body, headers or payload", runMask},
+	{"validate", "Check recorded payloads against the Treblle payload schema", runValidate},
+	{"openapi", "Generate an OpenAPI 3.1 document from captured events", runOpenAPI},
+	{"proxy", "Proxy an API and show captured traffic live", runProxy},
+}
+
 func main() {
+	// Subcommands take precedence over the legacy flags
+	if len(os.Args) > 1 {
+		for _, cmd := range commands {
+			if os.Args[1] == cmd.name {
+				os.Exit(cmd.run(os.Args[2:]))
+			}
+		}
+	}
+
 	// Define CLI flags
 	debug := flag.Bool("debug", false, "Show Treblle SDK debug information")
 
@@ -18,10 +47,27 @@ func main() {
 	if *debug {
 		treblle.DebugCommand()
 	} else {
-		fmt.Println("Usage: treblle-go -debug")
-		fmt.Println("\nTreblle Go SDK CLI")
-		fmt.Println("-------------------")
-		fmt.Println("Available commands:")
-		fmt.Println("  -debug    Show SDK configuration information")
+		printUsage()
+	}
+}
+
+// printUsage lists the available commands
+func printUsage() {
+	fmt.Println("Usage: treblle-go <command> [flags]")
+	fmt.Println("\nTreblle Go SDK CLI")
+	fmt.Println("-------------------")
+	fmt.Println("Available commands:")
+	fmt.Println("  -debug     Show SDK configuration information")
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Println("\nRun 'treblle-go <command> -h' for the flags of a command.")
+}
+
+// flagExitCode returns the exit status for a flag parsing error; asking for help is not a failure
+func flagExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
 	}
+	return 2
 }
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// Input kinds accepted by `treblle-go mask`
+const (
+	maskInputAuto    = "auto"
+	maskInputBody    = "body"
+	maskInputHeaders = "headers"
+	maskInputPayload = "payload"
+)
+
+// runMask implements `treblle-go mask`
+func runMask(args []string) int {
+	fs := flag.NewFlagSet("mask", flag.ContinueOnError)
+	kind := fs.String("type", maskInputAuto, "Input type: auto, body, headers or payload")
+	fields := fs.String("fields", "", "Comma-separated AdditionalFieldsToMask, as passed to Configure")
+	defaultFields := fs.String("default-fields", "", "Comma-separated DefaultFieldsToMask, replacing the SDK defaults")
+	configFile := fs.String("config", "", "Config file with the masking rules (default: $TREBLLE_CONFIG)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go mask [flags] [file]")
+		fmt.Fprintln(fs.Output(), "Reads from stdin if no file is given. The masked input is written to stdout and the report to stderr.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	var input []byte
+	var err error
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		input, err = io.ReadAll(os.Stdin)
+	} else {
+		input, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	treblle.Configure(treblle.Configuration{
+		AdditionalFieldsToMask: splitFields(*fields),
+		DefaultFieldsToMask:    splitFields(*defaultFields),
+		ConfigFile:             *configFile,
+	})
+
+	output, masked, err := maskInput(input, *kind)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(output)
+	printMaskReport(os.Stderr, masked)
+	return 0
+}
+
+// maskInput masks a body, a header set or a complete Treblle payload with the configured masking
+// and returns the indented result with the masked fields
+func maskInput(input []byte, kind string) ([]byte, []treblle.MaskedField, error) {
+	if kind == maskInputAuto {
+		kind = detectMaskInput(input)
+	}
+
+	var output interface{}
+	var masked []treblle.MaskedField
+	switch kind {
+	case maskInputBody:
+		body, fields, err := treblle.MaskJSON(input)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		output, masked = body, fields
+	case maskInputHeaders:
+		header, err := parseHeaders(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		output, masked = treblle.MaskHeaders(header)
+	case maskInputPayload:
+		var payload map[string]interface{}
+		if err := json.Unmarshal(input, &payload); err != nil {
+			return nil, nil, fmt.Errorf("invalid payload: %w", err)
+		}
+		fields, err := maskPayload(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		output, masked = payload, fields
+	default:
+		return nil, nil, fmt.Errorf("unknown input type %q", kind)
+	}
+
+	result, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(result, '\n'), masked, nil
+}
+
+// detectMaskInput guesses the input type: non-JSON input is a header set and JSON with
+// data.request is a Treblle payload
+func detectMaskInput(input []byte) string {
+	var document interface{}
+	if json.Unmarshal(input, &document) != nil {
+		return maskInputHeaders
+	}
+	if object, ok := document.(map[string]interface{}); ok {
+		if data, ok := object["data"].(map[string]interface{}); ok {
+			if _, ok := data["request"]; ok {
+				return maskInputPayload
+			}
+		}
+	}
+	return maskInputBody
+}
+
+// parseHeaders reads a JSON object of header values or "Name: value" lines
+func parseHeaders(input []byte) (http.Header, error) {
+	var object map[string]interface{}
+	if json.Unmarshal(input, &object) == nil {
+		return headersFromJSON(object)
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(bytes.TrimSpace(input)), strings.NewReader("\r\n\r\n"))))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid headers: %w", err)
+	}
+	return http.Header(header), nil
+}
+
+// headersFromJSON converts headers recorded as JSON strings or arrays of strings
+func headersFromJSON(object map[string]interface{}) (http.Header, error) {
+	header := http.Header{}
+	for name, value := range object {
+		switch value := value.(type) {
+		case string:
+			header[name] = []string{value}
+		case []interface{}:
+			for _, item := range value {
+				header[name] = append(header[name], fmt.Sprint(item))
+			}
+		default:
+			return nil, fmt.Errorf("header %q must be a string or an array of strings", name)
+		}
+	}
+	return header, nil
+}
+
+// maskPayload masks the headers and bodies of a payload's request and response in place
+func maskPayload(payload map[string]interface{}) ([]treblle.MaskedField, error) {
+	data, _ := payload["data"].(map[string]interface{})
+	var masked []treblle.MaskedField
+	for _, section := range []string{"request", "response"} {
+		info, ok := data[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		prefix := "data." + section
+
+		if headers, ok := info["headers"].(map[string]interface{}); ok {
+			header, err := headersFromJSON(headers)
+			if err != nil {
+				return nil, fmt.Errorf("%s.headers: %w", prefix, err)
+			}
+			maskedHeaders, fields := treblle.MaskHeaders(header)
+			info["headers"] = maskedHeaders
+			masked = append(masked, prefixMaskedFields(prefix+".headers", fields)...)
+		}
+
+		switch body := info["body"].(type) {
+		case map[string]interface{}, []interface{}:
+			raw, _ := json.Marshal(body)
+			maskedBody, fields, err := treblle.MaskJSON(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s.body: %w", prefix, err)
+			}
+			info["body"] = maskedBody
+			masked = append(masked, prefixMaskedFields(prefix+".body", fields)...)
+		}
+	}
+	return masked, nil
+}
+
+// prefixMaskedFields places masked paths under prefix
+func prefixMaskedFields(prefix string, fields []treblle.MaskedField) []treblle.MaskedField {
+	for i := range fields {
+		fields[i].Path = joinMaskedPath(prefix, fields[i].Path)
+	}
+	return fields
+}
+
+// joinMaskedPath places a path reported by the masking code under prefix
+func joinMaskedPath(prefix, path string) string {
+	if path == "" || strings.HasPrefix(path, "[") {
+		return prefix + path
+	}
+	return prefix + "." + path
+}
+
+// printMaskReport lists every masked path and the rule that masked it
+func printMaskReport(w io.Writer, masked []treblle.MaskedField) {
+	if len(masked) == 0 {
+		fmt.Fprintln(w, "No fields were masked")
+		return
+	}
+
+	width := 0
+	for _, field := range masked {
+		if len(field.Path) > width {
+			width = len(field.Path)
+		}
+	}
+	fmt.Fprintf(w, "Masked %d values:\n", len(masked))
+	for _, field := range masked {
+		fmt.Fprintf(w, "  %-*s  %s (%s)\n", width, field.Path, field.Field, field.Rule)
+	}
+}
+
+// splitFields splits a comma-separated flag value
+func splitFields(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Treblle/treblle-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useMaskConfig configures masking for a test and restores the SDK configuration afterwards
+func useMaskConfig(t *testing.T, envFields string, config treblle.Configuration) {
+	original := treblle.Config
+	t.Cleanup(func() { treblle.Config = original })
+	t.Setenv("TREBLLE_MASKED_FIELDS", envFields)
+
+	treblle.Configure(config)
+}
+
+func TestMaskBody(t *testing.T) {
+	useMaskConfig(t, "pin", treblle.Configuration{})
+
+	output, masked, err := maskInput([]byte(`{"user":{"password":"secret","pin":"1234","name":"Ann"}}`), maskInputAuto)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"user":{"password":"*********","pin":"*********","name":"Ann"}}`, string(output))
+	assert.Equal(t, []treblle.MaskedField{
+		{Path: "user.password", Field: "password", Rule: "DefaultFieldsToMask"},
+		{Path: "user.pin", Field: "pin", Rule: "TREBLLE_MASKED_FIELDS"},
+	}, masked)
+}
+
+func TestMaskHeaderLines(t *testing.T) {
+	useMaskConfig(t, "", treblle.Configuration{AdditionalFieldsToMask: []string{"x-session"}})
+
+	output, masked, err := maskInput([]byte("Authorization: Bearer abc\nSession: s1\nAccept: */*\n"), maskInputAuto)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"Authorization":"Bearer *********","Session":"*********","Accept":"*/*"}`, string(output))
+	assert.Equal(t, []treblle.MaskedField{
+		{Path: "Authorization", Field: "authorization", Rule: "DefaultFieldsToMask"},
+		{Path: "Session", Field: "x-session", Rule: "AdditionalFieldsToMask"},
+	}, masked)
+}
+
+func TestMaskPayload(t *testing.T) {
+	useMaskConfig(t, "", treblle.Configuration{DefaultFieldsToMask: []string{"authorization", "token"}})
+
+	payload := `{"api_key":"k","data":{
+		"request":{"headers":{"Authorization":"Basic abc"},"body":[{"token":"t"}]},
+		"response":{"headers":{},"body":"plain text"}}}`
+	output, masked, err := maskInput([]byte(payload), maskInputAuto)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"api_key":"k","data":{
+		"request":{"headers":{"Authorization":"Basic *********"},"body":[{"token":"*********"}]},
+		"response":{"headers":{},"body":"plain text"}}}`, string(output))
+	assert.Equal(t, []treblle.MaskedField{
+		{Path: "data.request.headers.Authorization", Field: "authorization", Rule: "DefaultFieldsToMask"},
+		{Path: "data.request.body[0].token", Field: "token", Rule: "DefaultFieldsToMask"},
+	}, masked)
+
+	// A body given with -type body must be JSON
+	_, _, err = maskInput([]byte("plain text"), maskInputBody)
+	assert.Error(t, err)
+}
+
+func TestPrintMaskReport(t *testing.T) {
+	var out bytes.Buffer
+	printMaskReport(&out, []treblle.MaskedField{
+		{Path: "password", Field: "password", Rule: "DefaultFieldsToMask"},
+		{Path: "user.pin", Field: "pin", Rule: "TREBLLE_MASKED_FIELDS"},
+	})
+	assert.Equal(t, "Masked 2 values:\n  password  password (DefaultFieldsToMask)\n  user.pin  pin (TREBLLE_MASKED_FIELDS)\n", out.String())
+
+	out.Reset()
+	printMaskReport(&out, nil)
+	assert.Equal(t, "No fields were masked\n", out.String())
+}
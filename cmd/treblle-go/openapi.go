@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// openAPIDocument is an OpenAPI 3.1 document
+type openAPIDocument struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    openAPIInfo                             `json:"info"`
+	Servers []openAPIServer                         `json:"servers,omitempty"`
+	Paths   map[string]map[string]*openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+// operationSamples collects the events recorded for one method and route
+type operationSamples struct {
+	method string
+	route  string
+	events []treblle.MetaData
+}
+
+// runOpenAPI implements `treblle-go openapi`
+func runOpenAPI(args []string) int {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	title := fs.String("title", "Captured API", "Title of the API")
+	version := fs.String("version", "1.0.0", "Version of the API")
+	server := fs.String("server", "", "Server URL to list in the document")
+	outPath := fs.String("out", "", "Write the document to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go openapi [flags] <events.json|events.jsonl>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	events, err := loadEvents(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	document := buildOpenAPI(events, openAPIInfo{Title: *title, Version: *version})
+	if *server != "" {
+		document.Servers = []openAPIServer{{URL: *server}}
+	}
+	output, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	output = append(output, '\n')
+
+	if *outPath == "" {
+		os.Stdout.Write(output)
+		return 0
+	}
+	if err := os.WriteFile(*outPath, output, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// buildOpenAPI groups events by method and normalized route and describes each operation
+func buildOpenAPI(events []treblle.MetaData, info openAPIInfo) *openAPIDocument {
+	var operations []*operationSamples
+	index := map[string]*operationSamples{}
+	for _, event := range events {
+		method := strings.ToUpper(event.Data.Request.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		route := openAPIRoute(event.Data.Request)
+		key := method + " " + route
+		if index[key] == nil {
+			index[key] = &operationSamples{method: method, route: route}
+			operations = append(operations, index[key])
+		}
+		index[key].events = append(index[key].events, event)
+	}
+
+	document := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   map[string]map[string]*openAPIOperation{},
+	}
+	for _, samples := range operations {
+		if document.Paths[samples.route] == nil {
+			document.Paths[samples.route] = map[string]*openAPIOperation{}
+		}
+		document.Paths[samples.route][strings.ToLower(samples.method)] = buildOperation(samples)
+	}
+	return document
+}
+
+// openAPIRoute returns the normalized route of a recorded request with unique parameter names
+func openAPIRoute(recorded treblle.RequestInfo) string {
+	route := recorded.RoutePath
+	if route == "" {
+		route, _ = recordedPathAndQuery(recorded)
+	}
+	route = treblle.NormalizeRoutePath(route)
+
+	// Route rules name every numeric segment {id}; OpenAPI needs unique names
+	segments := strings.Split(route, "/")
+	seen := map[string]int{}
+	for i, segment := range segments {
+		name, ok := routeParameter(segment)
+		if !ok {
+			continue
+		}
+		seen[name]++
+		if seen[name] > 1 {
+			segments[i] = "{" + name + strconv.Itoa(seen[name]) + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// routeParameter returns the parameter name of a {name} route segment
+func routeParameter(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// buildOperation describes the parameters, request body and responses seen for an operation
+func buildOperation(samples *operationSamples) *openAPIOperation {
+	operation := &openAPIOperation{
+		OperationID: operationID(samples.method, samples.route),
+		Parameters:  append(pathParameters(samples), queryParameters(samples)...),
+		Responses:   map[string]*openAPIResponse{},
+	}
+
+	requestBody := newInferredSchema()
+	withBody := 0
+	responses := map[int]*inferredSchema{}
+	for _, event := range samples.events {
+		if body, ok := decodeRecordedBody(event.Data.Request.Body); ok {
+			requestBody.observe(body)
+			withBody++
+		}
+
+		code := event.Data.Response.Code
+		if code == 0 {
+			code = http.StatusOK
+		}
+		if responses[code] == nil {
+			responses[code] = newInferredSchema()
+		}
+		if body, ok := decodeRecordedBody(event.Data.Response.Body); ok {
+			responses[code].observe(body)
+		}
+	}
+
+	if withBody > 0 {
+		operation.RequestBody = &openAPIRequestBody{
+			Required: withBody == len(samples.events),
+			Content:  map[string]openAPIMediaType{"application/json": {Schema: requestBody.schema()}},
+		}
+	}
+	for code, body := range responses {
+		response := &openAPIResponse{Description: http.StatusText(code)}
+		if response.Description == "" {
+			response.Description = "Status " + strconv.Itoa(code)
+		}
+		if len(body.types) > 0 {
+			response.Content = map[string]openAPIMediaType{"application/json": {Schema: body.schema()}}
+		}
+		operation.Responses[strconv.Itoa(code)] = response
+	}
+	return operation
+}
+
+// pathParameters describes the route parameters, inferring their type from the recorded paths
+func pathParameters(samples *operationSamples) []openAPIParameter {
+	segments := strings.Split(samples.route, "/")
+	var parameters []openAPIParameter
+	for i, segment := range segments {
+		name, ok := routeParameter(segment)
+		if !ok {
+			continue
+		}
+
+		values := newInferredSchema()
+		for _, event := range samples.events {
+			path, _ := recordedPathAndQuery(event.Data.Request)
+			recorded := strings.Split(path, "/")
+			if len(recorded) == len(segments) {
+				values.observe(parameterValue(recorded[i]))
+			}
+		}
+		parameters = append(parameters, openAPIParameter{Name: name, In: "path", Required: true, Schema: parameterSchema(values)})
+	}
+	return parameters
+}
+
+// queryParameters describes the query string parameters; parameters sent with every request are
+// required and parameters repeated in a request are arrays
+func queryParameters(samples *operationSamples) []openAPIParameter {
+	values := map[string]*inferredSchema{}
+	present := map[string]int{}
+	for _, event := range samples.events {
+		_, rawQuery := recordedPathAndQuery(event.Data.Request)
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			continue
+		}
+		for name, items := range query {
+			if values[name] == nil {
+				values[name] = newInferredSchema()
+			}
+			present[name]++
+			if len(items) > 1 {
+				array := make([]interface{}, len(items))
+				for i, item := range items {
+					array[i] = parameterValue(item)
+				}
+				values[name].observe(array)
+			} else {
+				values[name].observe(parameterValue(items[0]))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameters := make([]openAPIParameter, 0, len(names))
+	for _, name := range names {
+		parameters = append(parameters, openAPIParameter{
+			Name:     name,
+			In:       "query",
+			Required: present[name] == len(samples.events),
+			Schema:   parameterSchema(values[name]),
+		})
+	}
+	return parameters
+}
+
+// parameterValue converts a path or query value to the JSON type it most likely represents
+func parameterValue(value string) interface{} {
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "xXeE") {
+		return number
+	}
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	return value
+}
+
+// parameterSchema returns the schema of a parameter, defaulting to string when nothing was seen
+func parameterSchema(values *inferredSchema) *jsonSchema {
+	schema := values.schema()
+	if len(schema.Type) == 0 {
+		schema.Type = schemaTypes{"string"}
+	}
+	return schema
+}
+
+// decodeRecordedBody decodes a recorded body; missing and null bodies are reported as absent
+func decodeRecordedBody(body json.RawMessage) (interface{}, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, false
+	}
+	var value interface{}
+	if err := json.Unmarshal(trimmed, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// operationID derives an identifier such as getUsersById from a method and route
+func operationID(method, route string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(route, "/") {
+		if name, ok := routeParameter(segment); ok {
+			id.WriteString("By")
+			segment = name
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return id.String()
+}
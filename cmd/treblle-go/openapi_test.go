@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Treblle/treblle-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedEvent returns an event as recorded by the middleware
+func capturedEvent(method, url, routePath, requestBody string, code int, responseBody string) treblle.MetaData {
+	return treblle.MetaData{
+		Data: treblle.DataInfo{
+			Request: treblle.RequestInfo{
+				Method:    method,
+				Url:       url,
+				RoutePath: routePath,
+				Body:      json.RawMessage(requestBody),
+			},
+			Response: treblle.ResponseInfo{Code: code, Body: json.RawMessage(responseBody)},
+		},
+	}
+}
+
+func TestBuildOpenAPI(t *testing.T) {
+	events := []treblle.MetaData{
+		capturedEvent("GET", "http://api.test/users/1?expand=true", "/users/{id}", "", 200, `{"id":1,"name":"Ada","email":"ada@example.com"}`),
+		capturedEvent("GET", "http://api.test/users/2", "/users/{id}", "", 200, `{"id":2,"name":"Bob","nickname":null}`),
+		capturedEvent("GET", "http://api.test/users/3", "/users/{id}", "", 404, `{"error":"not found"}`),
+		capturedEvent("POST", "http://api.test/users", "/users", `{"name":"Cy","age":41.5}`, 201, `{"id":3}`),
+		capturedEvent("POST", "http://api.test/users", "/users", `{"name":"Di","age":30,"tags":["a"]}`, 201, `{"id":4}`),
+	}
+
+	document := buildOpenAPI(events, openAPIInfo{Title: "Users", Version: "1.0.0"})
+	assert.Equal(t, "3.1.0", document.OpenAPI)
+	require.Contains(t, document.Paths, "/users/{id}")
+	require.Contains(t, document.Paths, "/users")
+
+	get := document.Paths["/users/{id}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "getUsersById", get.OperationID)
+	assert.Nil(t, get.RequestBody)
+	assert.Equal(t, []openAPIParameter{
+		{Name: "id", In: "path", Required: true, Schema: &jsonSchema{Type: schemaTypes{"integer"}}},
+		{Name: "expand", In: "query", Required: false, Schema: &jsonSchema{Type: schemaTypes{"boolean"}}},
+	}, get.Parameters)
+
+	ok := get.Responses["200"].Content["application/json"].Schema
+	assert.Equal(t, []string{"id", "name"}, ok.Required, "fields missing from a sample are optional")
+	assert.Equal(t, schemaTypes{"null"}, ok.Properties["nickname"].Type)
+	assert.Equal(t, "email", ok.Properties["email"].Format)
+	assert.Equal(t, "Not Found", get.Responses["404"].Description)
+
+	post := document.Paths["/users"]["post"]
+	require.NotNil(t, post)
+	require.NotNil(t, post.RequestBody)
+	assert.True(t, post.RequestBody.Required)
+	body := post.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"age", "name"}, body.Required)
+	assert.Equal(t, schemaTypes{"number"}, body.Properties["age"].Type, "integer and number merge into number")
+	assert.Equal(t, schemaTypes{"string"}, body.Properties["tags"].Items.Type)
+	assert.Contains(t, post.Responses, "201")
+}
+
+func TestOpenAPIRouteNormalization(t *testing.T) {
+	// Literal paths go through the route rules and repeated placeholders get unique names
+	route := openAPIRoute(treblle.RequestInfo{Url: "http://api.test/users/7/posts/9"})
+	assert.Equal(t, "/users/{id}/posts/{id2}", route)
+
+	assert.Equal(t, "/orders/{orderId}", openAPIRoute(treblle.RequestInfo{RoutePath: "orders/:orderId"}))
+	assert.Equal(t, "getUsersByIdPostsById2", operationID("GET", "/users/{id}/posts/{id2}"))
+}
+
+func TestInferredSchemaUnions(t *testing.T) {
+	schema := newInferredSchema()
+	schema.observe("2024-01-02")
+	schema.observe(float64(3))
+	schema.observe(nil)
+
+	result := schema.schema()
+	assert.Equal(t, schemaTypes{"integer", "null", "string"}, result.Type)
+	assert.Equal(t, "date", result.Format)
+
+	schema.observe("plain")
+	assert.Empty(t, schema.schema().Format, "strings with different formats have no format")
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// ANSI colors used by the terminal renderer
+const (
+	colorReset  = "\033[0m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// terminalExporter renders every captured event as it is delivered
+type terminalExporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	preview int  // Maximum number of body characters shown
+	color   bool // Use ANSI colors
+}
+
+// runProxy implements `treblle-go proxy`
+func runProxy(args []string) int {
+	fs := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	listen := fs.String("listen", ":8080", "Address to listen on")
+	target := fs.String("target", "", "URL of the API to proxy to, e.g. http://localhost:3000 (required)")
+	forward := fs.Bool("forward", false, "Also send captured events to Treblle")
+	sdkToken := fs.String("sdk-token", "", "SDK token used with -forward (default: $TREBLLE_SDK_TOKEN)")
+	apiKey := fs.String("api-key", "", "API key used with -forward")
+	endpoint := fs.String("endpoint", "", "Custom Treblle endpoint used with -forward, e.g. a local collector")
+	outPath := fs.String("out", "", "Append captured events to this JSONL file")
+	configFile := fs.String("config", "", "Config file with masking, ignored routes and sampling (default: $TREBLLE_CONFIG)")
+	preview := fs.Int("preview", 120, "Maximum number of body characters shown (0 hides bodies)")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go proxy -target <url> [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *target == "" {
+		fs.Usage()
+		return 2
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		fmt.Fprintf(os.Stderr, "invalid -target %q\n", *target)
+		return 2
+	}
+
+	exporters := []treblle.Exporter{&terminalExporter{
+		w:       os.Stdout,
+		preview: *preview,
+		color:   !*noColor && os.Getenv("NO_COLOR") == "",
+	}}
+	if *outPath != "" {
+		file, err := treblle.NewFileExporter(*outPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		exporters = append(exporters, file)
+	}
+
+	treblle.Configure(treblle.Configuration{
+		SDK_TOKEN:      *sdkToken,
+		API_KEY:        *apiKey,
+		Endpoint:       *endpoint,
+		Exporters:      exporters,
+		DisableTreblle: !*forward,
+		ConfigFile:     *configFile,
+	})
+	if *forward && treblle.Config.APIKey == "" {
+		fmt.Fprintln(os.Stderr, "-forward requires an SDK token from -sdk-token, TREBLLE_SDK_TOKEN or the config file")
+		return 2
+	}
+	if treblle.IsEnvironmentIgnored() {
+		fmt.Fprintln(os.Stderr, "the current environment is ignored by Treblle, so no traffic would be captured; set TREBLLE_IGNORED_ENV to a list without it")
+		return 1
+	}
+
+	log.Printf("Proxying %s to %s", *listen, targetURL)
+	if err := http.ListenAndServe(*listen, newProxyHandler(targetURL)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// newProxyHandler returns a reverse proxy to target wrapped in the Treblle middleware
+func newProxyHandler(target *url.URL) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+	}
+	return treblle.Middleware(proxy)
+}
+
+// Export renders one event
+func (e *terminalExporter) Export(event treblle.MetaData) error {
+	request, response := event.Data.Request, event.Data.Response
+
+	path, rawQuery := recordedPathAndQuery(request)
+	if rawQuery != "" {
+		path += "?" + rawQuery
+	}
+	route, actual := request.RoutePath, ""
+	if route == "" {
+		route = path
+	} else if path != route {
+		actual = " " + e.paint(colorDim, path)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s %-7s %s %s %s%s\n",
+		e.paint(colorDim, time.Now().Format("15:04:05")),
+		request.Method,
+		e.paint(statusColor(response.Code), fmt.Sprintf("%d", response.Code)),
+		e.paint(colorCyan, route),
+		fmt.Sprintf("%.1fms", response.LoadTime),
+		actual)
+	if body := bodyPreview(request.Body, e.preview); body != "" {
+		fmt.Fprintf(&out, "  → %s\n", body)
+	}
+	if body := bodyPreview(response.Body, e.preview); body != "" {
+		fmt.Fprintf(&out, "  ← %s\n", body)
+	}
+	for _, info := range response.Errors {
+		fmt.Fprintf(&out, "  %s %s: %s (%s)\n", e.paint(colorRed, "✗"), info.Type, info.Message, info.Source)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.w.Write(out.Bytes())
+	return err
+}
+
+// paint colors text if colors are enabled
+func (e *terminalExporter) paint(color, text string) string {
+	if !e.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// statusColor returns the color for a status code
+func statusColor(code int) string {
+	switch {
+	case code >= 500:
+		return colorRed
+	case code >= 400:
+		return colorYellow
+	default:
+		return colorGreen
+	}
+}
+
+// bodyPreview returns a single-line preview of a recorded body of at most limit characters
+func bodyPreview(body json.RawMessage, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	trimmed := recordedBody(body)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("{}")) {
+		return ""
+	}
+
+	var compact bytes.Buffer
+	if json.Compact(&compact, trimmed) == nil {
+		trimmed = compact.Bytes()
+	}
+	preview := []rune(strings.Join(strings.Fields(string(trimmed)), " "))
+	if len(preview) > limit {
+		return string(preview[:limit]) + "…"
+	}
+	return string(preview)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Treblle/treblle-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestProxyRendersCapturedTraffic(t *testing.T) {
+	useDoctorConfig(t)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/users/42", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":42,"password":"secret"}`))
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	var out syncBuffer
+	treblle.Configure(treblle.Configuration{
+		Exporters:      []treblle.Exporter{&terminalExporter{w: &out, preview: 60}},
+		DisableTreblle: true,
+	})
+
+	proxy := httptest.NewServer(newProxyHandler(target))
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL+"/users/42?verbose=1", "application/json", strings.NewReader(`{"name":"Ada"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	assert.Eventually(t, func() bool { return strings.Contains(out.String(), "←") }, time.Second, 10*time.Millisecond)
+	require.NoError(t, treblle.Shutdown(context.Background()))
+	output := out.String()
+	assert.Regexp(t, `POST\s+201 /users/\{id\} [0-9.]+ms /users/42\?verbose=1`, output)
+	assert.Contains(t, output, `→ {"name":"Ada"}`)
+	assert.Contains(t, output, `← {"id":42,"password":"*********"}`, "bodies are shown masked")
+}
+
+func TestTerminalExporterErrorsAndPreview(t *testing.T) {
+	var out bytes.Buffer
+	exporter := &terminalExporter{w: &out, preview: 10, color: true}
+
+	event := treblle.MetaData{Data: treblle.DataInfo{
+		Request: treblle.RequestInfo{Method: "GET", Url: "http://localhost/health"},
+		Response: treblle.ResponseInfo{
+			Code:     500,
+			LoadTime: 1.25,
+			Body:     json.RawMessage(`{"message": "database unavailable"}`),
+			Errors:   []treblle.ErrorInfo{{Message: "db down", Type: treblle.ServerError, Source: "handler"}},
+		},
+	}}
+	require.NoError(t, exporter.Export(event))
+
+	output := out.String()
+	assert.Contains(t, output, colorRed+"500"+colorReset)
+	assert.Contains(t, output, "← {\"message\"…")
+	assert.Contains(t, output, "db down (handler)")
+	assert.NotContains(t, output, "→", "empty request bodies are not shown")
+}
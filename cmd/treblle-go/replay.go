@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// skippedReplayHeaders are recorded headers that describe the original connection
+var skippedReplayHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"Accept-Encoding":   true,
+}
+
+// maskedValue is the placeholder the SDK records in place of masked values
+const maskedValue = "*********"
+
+// headerFlags collects repeated -header "Name: value" flags
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q must be in the form \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// replayResult is the outcome of replaying a single event
+type replayResult struct {
+	method         string
+	path           string
+	expectedStatus int
+	actualStatus   int
+	diffs          []string
+	err            error
+}
+
+// runReplay implements `treblle-go replay`
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	target := fs.String("target", "", "Base URL to replay requests against, e.g. http://localhost:8080 (required)")
+	concurrency := fs.Int("concurrency", 4, "Number of requests in flight at once")
+	rate := fs.Float64("rate", 0, "Maximum requests per second (0 = unlimited)")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout for each request")
+	failOnDiff := fs.Bool("fail", false, "Exit with status 1 if any response differs from the recording")
+	var headers headerFlags
+	fs.Var(&headers, "header", "Header to set on every request, e.g. \"Authorization: Bearer token\" (repeatable). Masked headers are not replayed and must be supplied again this way")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go replay -target <url> [flags] <events.json|events.jsonl>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *target == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	base, err := url.Parse(*target)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		fmt.Fprintf(os.Stderr, "invalid -target %q\n", *target)
+		return 2
+	}
+
+	events, err := loadEvents(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	start := time.Now()
+	results := replayEvents(client, base, events, headers, *concurrency, *rate)
+
+	differences := printReplayResults(os.Stdout, results)
+	fmt.Printf("\nReplayed %d events against %s in %s\n", len(results), base, time.Since(start).Round(time.Millisecond))
+	printReplaySummary(os.Stdout, results)
+
+	if differences > 0 && *failOnDiff {
+		return 1
+	}
+	return 0
+}
+
+// replayEvents re-issues every event with at most concurrency requests in flight and at most
+// rate requests per second. Results are returned in the order of the events.
+func replayEvents(client *http.Client, base *url.URL, events []treblle.MetaData, headers []string, concurrency int, rate float64) []replayResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]replayResult, len(events))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index] = replayEvent(client, base, events[index], headers)
+			}
+		}()
+	}
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for index := range events {
+		if tick != nil && index > 0 {
+			<-tick
+		}
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// replayEvent sends the recorded request and compares the response with the recording
+func replayEvent(client *http.Client, base *url.URL, event treblle.MetaData, headers []string) replayResult {
+	result := replayResult{
+		method:         event.Data.Request.Method,
+		expectedStatus: event.Data.Response.Code,
+	}
+
+	req, err := newReplayRequest(context.Background(), base, event.Data.Request, headers)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.path = req.URL.RequestURI()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	result.actualStatus = resp.StatusCode
+	result.diffs = shapeDiff(decodeBody(event.Data.Response.Body), decodeBody(body))
+	return result
+}
+
+// newReplayRequest rebuilds a recorded request against base
+func newReplayRequest(ctx context.Context, base *url.URL, recorded treblle.RequestInfo, headers []string) (*http.Request, error) {
+	method := recorded.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	path, rawQuery := recordedPathAndQuery(recorded)
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + path
+	target.RawPath = ""
+	target.RawQuery = rawQuery
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(recordedBody(recorded.Body)))
+	if err != nil {
+		return nil, err
+	}
+
+	var recordedHeaders map[string]interface{}
+	if len(recorded.Headers) > 0 {
+		if err := json.Unmarshal(recorded.Headers, &recordedHeaders); err != nil {
+			return nil, fmt.Errorf("invalid recorded headers: %w", err)
+		}
+	}
+	for name, value := range recordedHeaders {
+		if skippedReplayHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		switch value := value.(type) {
+		case string:
+			if !isMaskedHeaderValue(value) {
+				req.Header.Set(name, value)
+			}
+		case []interface{}:
+			for _, item := range value {
+				if item := fmt.Sprint(item); !isMaskedHeaderValue(item) {
+					req.Header.Add(name, item)
+				}
+			}
+		}
+	}
+
+	for _, header := range headers {
+		name, value, _ := strings.Cut(header, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return req, nil
+}
+
+// isMaskedHeaderValue reports whether a recorded header value was masked by the SDK, either
+// entirely or after an authorization scheme as in "Bearer *********". Masked values are not
+// replayed; they must be supplied again with -header.
+func isMaskedHeaderValue(value string) bool {
+	if value == maskedValue {
+		return true
+	}
+	_, credentials, found := strings.Cut(value, " ")
+	return found && credentials == maskedValue
+}
+
+// recordedPathAndQuery returns the path and query string of a recorded request, falling back to
+// the route path and the SDK's {"query": "..."} field
+func recordedPathAndQuery(recorded treblle.RequestInfo) (string, string) {
+	var path, rawQuery string
+	if u, err := url.Parse(recorded.Url); err == nil {
+		path = u.Path
+		rawQuery = u.RawQuery
+	}
+	if path == "" {
+		path = recorded.RoutePath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	if rawQuery == "" && len(recorded.Query) > 0 {
+		var query map[string]interface{}
+		if err := json.Unmarshal(recorded.Query, &query); err == nil {
+			if value, ok := query["query"].(string); ok {
+				rawQuery = value
+			}
+		}
+	}
+	return path, rawQuery
+}
+
+// recordedBody returns the bytes to send for a recorded body; JSON strings are sent unquoted
+func recordedBody(body json.RawMessage) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	var text string
+	if trimmed[0] == '"' && json.Unmarshal(trimmed, &text) == nil {
+		return []byte(text)
+	}
+	return trimmed
+}
+
+// printReplayResults prints one line per event and returns how many did not match
+func printReplayResults(w io.Writer, results []replayResult) int {
+	differences := 0
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			differences++
+			fmt.Fprintf(w, "✗ %s %s: %v\n", result.method, result.path, result.err)
+		case result.actualStatus != result.expectedStatus || len(result.diffs) > 0:
+			differences++
+			fmt.Fprintf(w, "✗ %s %s: status %d → %d\n", result.method, result.path, result.expectedStatus, result.actualStatus)
+			for _, diff := range result.diffs {
+				fmt.Fprintf(w, "    %s\n", diff)
+			}
+		default:
+			fmt.Fprintf(w, "✓ %s %s: %d\n", result.method, result.path, result.actualStatus)
+		}
+	}
+	return differences
+}
+
+// printReplaySummary prints how many events matched and how they differed
+func printReplaySummary(w io.Writer, results []replayResult) {
+	var matched, statusMismatch, shapeMismatch, failed int
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			failed++
+		case result.actualStatus != result.expectedStatus:
+			statusMismatch++
+		case len(result.diffs) > 0:
+			shapeMismatch++
+		default:
+			matched++
+		}
+	}
+
+	fmt.Fprintf(w, "  matched:          %d\n", matched)
+	fmt.Fprintf(w, "  status mismatch:  %d\n", statusMismatch)
+	fmt.Fprintf(w, "  shape mismatch:   %d\n", shapeMismatch)
+	fmt.Fprintf(w, "  failed:           %d\n", failed)
+}
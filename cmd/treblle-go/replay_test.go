@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Treblle/treblle-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const replayEvent1 = `{"data":{"request":{"method":"POST","url":"https://api.example.com/users?verbose=1","headers":{"Content-Type":"application/json","Host":"api.example.com","X-Tenant":"acme"},"body":{"name":"Ada"}},"response":{"code":201,"body":{"id":1,"name":"Ada","tags":[{"id":1}]}}}}`
+const replayEvent2 = `{"data":{"request":{"method":"GET","route_path":"/users/{id}","url":"","query":{"query":"page=2"}},"response":{"code":200,"body":{"id":1}}}}`
+
+func TestReadEvents(t *testing.T) {
+	events, err := readEvents(strings.NewReader(replayEvent1 + "\n\n" + replayEvent2 + "\n"))
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = readEvents(strings.NewReader("[" + replayEvent1 + "," + replayEvent2 + "]"))
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = loadEvents([]string{"../../sample.json"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "GET", events[0].Data.Request.Method)
+
+	_, err = readEvents(strings.NewReader(replayEvent1 + "\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestShapeDiff(t *testing.T) {
+	expected := decodeBody([]byte(`{"id":1,"name":"Ada","tags":[{"id":1}],"deleted_at":null}`))
+	actual := decodeBody([]byte(`{"id":"1","tags":[{"id":1,"label":"x"}],"deleted_at":"2024-01-01","extra":true}`))
+
+	assert.Equal(t, []string{
+		"~$.id: number → string",
+		"-$.name",
+		"+$.tags[].label",
+		"+$.extra",
+	}, shapeDiff(expected, actual))
+
+	assert.Empty(t, shapeDiff(decodeBody([]byte(`"plain text"`)), decodeBody([]byte("other text"))))
+}
+
+func TestReplayEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/users":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"name":"Ada"}`, string(body))
+			assert.Equal(t, "1", r.URL.Query().Get("verbose"))
+			assert.Equal(t, "acme", r.Header.Get("X-Tenant"))
+			assert.Equal(t, "Bearer test", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "name": "Ada", "tags": []interface{}{}})
+		default:
+			assert.Equal(t, "page=2", r.URL.RawQuery)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	events, err := readEvents(strings.NewReader(replayEvent1 + "\n" + replayEvent2))
+	require.NoError(t, err)
+
+	base, _ := url.Parse(server.URL + "/api")
+	results := replayEvents(server.Client(), base, events, []string{"Authorization: Bearer test"}, 2, 100)
+	require.Len(t, results, 2)
+
+	assert.NoError(t, results[0].err)
+	assert.Equal(t, "/api/users?verbose=1", results[0].path)
+	assert.Equal(t, 201, results[0].actualStatus)
+	assert.Empty(t, results[0].diffs)
+
+	assert.Equal(t, "/api/users/%7Bid%7D?page=2", results[1].path)
+	assert.Equal(t, 200, results[1].expectedStatus)
+	assert.Equal(t, 404, results[1].actualStatus)
+	assert.Equal(t, []string{"-$.id", "+$.error"}, results[1].diffs)
+
+	var out strings.Builder
+	assert.Equal(t, 1, printReplayResults(&out, results))
+	assert.Contains(t, out.String(), "✗ GET /api/users/%7Bid%7D?page=2: status 200 → 404")
+}
+
+func TestNewReplayRequestSkipsMaskedHeaders(t *testing.T) {
+	recorded := treblle.RequestInfo{
+		Method:  http.MethodGet,
+		Url:     "https://api.example.com/me",
+		Headers: json.RawMessage(`{"Authorization":"Bearer *********","X-Api-Key":"*********","Cookie":["*********","theme=dark"],"X-Tenant":"acme"}`),
+	}
+	base, _ := url.Parse("http://localhost:8080")
+
+	req, err := newReplayRequest(context.Background(), base, recorded, nil)
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-Api-Key"))
+	assert.Equal(t, []string{"theme=dark"}, req.Header.Values("Cookie"))
+	assert.Equal(t, "acme", req.Header.Get("X-Tenant"))
+
+	req, err = newReplayRequest(context.Background(), base, recorded, []string{"Authorization: Bearer real"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer real", req.Header.Get("Authorization"))
+}
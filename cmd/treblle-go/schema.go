@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// payloadSchemaID identifies the generated schema of the Treblle wire format. It resolves to the
+// committed payload.schema.json on the main branch.
+const payloadSchemaID = "https://raw.githubusercontent.com/Treblle/treblle-go/main/cmd/treblle-go/payload.schema.json"
+
+// rawMessageType is accepted as any JSON value
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// eventSchema is the JSON Schema events are checked against
+var eventSchema = payloadSchema()
+
+// jsonSchema is the subset of JSON Schema (draft 2020-12) written by the CLI
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 schemaTypes            `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// schemaTypes is written as a single type name, or as a list when null is also allowed
+type schemaTypes []string
+
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// payloadSchema generates the JSON Schema of treblle.MetaData. Fields without omitempty are
+// required; json.RawMessage fields accept any value; slices, maps and pointers may be null.
+func payloadSchema() *jsonSchema {
+	defs := map[string]*jsonSchema{}
+	root := schemaFor(reflect.TypeOf(treblle.MetaData{}), defs)
+	root.Schema = "https://json-schema.org/draft/2020-12/schema"
+	root.ID = payloadSchemaID
+	root.Title = "Treblle payload"
+	root.Defs = defs
+	return root
+}
+
+// schemaFor returns the schema of t, adding named structs to defs
+func schemaFor(t reflect.Type, defs map[string]*jsonSchema) *jsonSchema {
+	if t == rawMessageType || t.Kind() == reflect.Interface {
+		return &jsonSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaFor(t.Elem(), defs)
+		if schema.Ref != "" {
+			return &jsonSchema{Type: schemaTypes{"object", "null"}, Ref: schema.Ref}
+		}
+		schema.Type = append(schema.Type, "null")
+		return schema
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // Reserve the name for recursive types
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return &jsonSchema{Ref: "#/$defs/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: schemaTypes{"array", "null"}, Items: schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return &jsonSchema{Type: schemaTypes{"object", "null"}, AdditionalProperties: schemaFor(t.Elem(), defs)}
+	default:
+		return &jsonSchema{Type: schemaTypes{schemaTypeName(t)}}
+	}
+}
+
+// structSchema describes the JSON object a struct is encoded as
+func structSchema(t reflect.Type, defs map[string]*jsonSchema) *jsonSchema {
+	schema := &jsonSchema{Type: schemaTypes{"object"}, Properties: map[string]*jsonSchema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		schema.Properties[name] = schemaFor(field.Type, defs)
+		if !omitempty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// checkEvent checks a decoded event against the payload schema
+func checkEvent(event interface{}) []string {
+	var problems []string
+	validateSchema(eventSchema, eventSchema, "", event, &problems)
+	return problems
+}
+
+// validateSchema appends a problem for every way value does not match schema at path
+func validateSchema(root, schema *jsonSchema, path string, value interface{}, problems *[]string) {
+	if schema.Ref != "" {
+		resolved := root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+		if value == nil && schema.Type.allows("null") {
+			return
+		}
+		validateSchema(root, resolved, path, value, problems)
+		return
+	}
+	if len(schema.Type) == 0 {
+		return
+	}
+
+	actual := jsonType(value)
+	if !schema.Type.accepts(value) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", displayPath(path), schema.Type[0], actual))
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, present := value[name]; !present {
+				*problems = append(*problems, fmt.Sprintf("%s: missing", displayPath(joinPath(path, name))))
+			}
+		}
+		for _, name := range sortedKeys(value) {
+			if property, ok := schema.Properties[name]; ok {
+				validateSchema(root, property, joinPath(path, name), value[name], problems)
+			} else if schema.AdditionalProperties != nil {
+				validateSchema(root, schema.AdditionalProperties, joinPath(path, name), value[name], problems)
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range value {
+				validateSchema(root, schema.Items, fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+	}
+}
+
+// allows reports whether name is one of the types
+func (t schemaTypes) allows(name string) bool {
+	for _, candidate := range t {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// accepts reports whether a decoded JSON value has one of the types
+func (t schemaTypes) accepts(value interface{}) bool {
+	actual := jsonType(value)
+	if t.allows(actual) {
+		return true
+	}
+	if number, ok := value.(float64); ok && t.allows("integer") {
+		return number == math.Trunc(number)
+	}
+	return false
+}
+
+// jsonFieldName returns the JSON name of a struct field and whether it is omitempty
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty"), true
+}
+
+// schemaTypeName names the JSON type used for a Go type
+func schemaTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Pointer:
+		return schemaTypeName(t.Elem())
+	default:
+		return "object"
+	}
+}
+
+// joinPath appends a field name to a dotted path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// displayPath names the document root
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// inferredSchema accumulates the JSON values seen at one position across samples
+type inferredSchema struct {
+	types      map[string]bool
+	objects    int                        // Number of samples that were objects
+	properties map[string]*inferredSchema // Values seen for each object key
+	items      *inferredSchema            // Values seen in arrays
+	present    int                        // Number of objects this property appeared in
+	format     string                     // Format shared by every string, if any
+	strings    int                        // Number of samples that were strings
+}
+
+// newInferredSchema returns an empty schema
+func newInferredSchema() *inferredSchema {
+	return &inferredSchema{types: map[string]bool{}, properties: map[string]*inferredSchema{}}
+}
+
+// observe adds a decoded JSON value to the schema
+func (s *inferredSchema) observe(value interface{}) {
+	switch value := value.(type) {
+	case nil:
+		s.types["null"] = true
+	case bool:
+		s.types["boolean"] = true
+	case float64:
+		if value == math.Trunc(value) {
+			s.types["integer"] = true
+		} else {
+			s.types["number"] = true
+		}
+	case string:
+		s.types["string"] = true
+		format := stringFormat(value)
+		if s.strings == 0 {
+			s.format = format
+		} else if s.format != format {
+			s.format = ""
+		}
+		s.strings++
+	case []interface{}:
+		s.types["array"] = true
+		if s.items == nil {
+			s.items = newInferredSchema()
+		}
+		for _, item := range value {
+			s.items.observe(item)
+		}
+	case map[string]interface{}:
+		s.types["object"] = true
+		s.objects++
+		for key, property := range value {
+			if s.properties[key] == nil {
+				s.properties[key] = newInferredSchema()
+			}
+			s.properties[key].observe(property)
+			s.properties[key].present++
+		}
+	}
+}
+
+// schema returns the JSON Schema of every value observed. Object properties present in every
+// sample are required; integer and number samples merge into number.
+func (s *inferredSchema) schema() *jsonSchema {
+	types := make([]string, 0, len(s.types))
+	for name := range s.types {
+		types = append(types, name)
+	}
+	if s.types["integer"] && s.types["number"] {
+		types = removeString(types, "integer")
+	}
+	sort.Strings(types)
+
+	schema := &jsonSchema{Type: types}
+	if s.types["string"] {
+		schema.Format = s.format
+	}
+	if s.items != nil {
+		schema.Items = s.items.schema()
+	}
+	if len(s.properties) > 0 {
+		schema.Properties = map[string]*jsonSchema{}
+		for _, key := range sortedSchemaKeys(s.properties) {
+			property := s.properties[key]
+			schema.Properties[key] = property.schema()
+			if property.present == s.objects {
+				schema.Required = append(schema.Required, key)
+			}
+		}
+	}
+	return schema
+}
+
+// stringFormat returns the JSON Schema format of a string value, if it has a recognizable one
+func stringFormat(value string) string {
+	switch {
+	case uuidPattern.MatchString(value):
+		return "uuid"
+	case datePattern.MatchString(value):
+		return "date"
+	}
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return "date-time"
+	}
+	if address, err := mail.ParseAddress(value); err == nil && address.Address == value {
+		return "email"
+	}
+	return ""
+}
+
+// removeString returns values without value
+func removeString(values []string, value string) []string {
+	result := values[:0]
+	for _, candidate := range values {
+		if candidate != value {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// sortedSchemaKeys returns the property names in order
+func sortedSchemaKeys(properties map[string]*inferredSchema) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
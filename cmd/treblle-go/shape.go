@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// decodeBody decodes a JSON body, treating anything that is not valid JSON as a string the way
+// the SDK records non-JSON responses
+func decodeBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	return value
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// shapeDiff compares the structure of two decoded JSON documents, ignoring values. Each
+// difference is reported on its own line: "-path" for a missing field, "+path" for an
+// unexpected one and "~path: type → type" for a changed type. Null matches any type and
+// arrays are compared by their first element.
+func shapeDiff(expected, actual interface{}) []string {
+	var diffs []string
+	compareShape("$", expected, actual, &diffs)
+	return diffs
+}
+
+// compareShape appends the differences between expected and actual at path to diffs
+func compareShape(path string, expected, actual interface{}, diffs *[]string) {
+	if expected == nil || actual == nil {
+		return
+	}
+
+	expectedType, actualType := jsonType(expected), jsonType(actual)
+	if expectedType != actualType {
+		*diffs = append(*diffs, fmt.Sprintf("~%s: %s → %s", path, expectedType, actualType))
+		return
+	}
+
+	switch expectedValue := expected.(type) {
+	case map[string]interface{}:
+		actualValue := actual.(map[string]interface{})
+		for _, key := range sortedKeys(expectedValue) {
+			if _, ok := actualValue[key]; !ok {
+				*diffs = append(*diffs, "-"+path+"."+key)
+				continue
+			}
+			compareShape(path+"."+key, expectedValue[key], actualValue[key], diffs)
+		}
+		for _, key := range sortedKeys(actualValue) {
+			if _, ok := expectedValue[key]; !ok {
+				*diffs = append(*diffs, "+"+path+"."+key)
+			}
+		}
+	case []interface{}:
+		actualValue := actual.([]interface{})
+		if len(expectedValue) > 0 && len(actualValue) > 0 {
+			compareShape(path+"[]", expectedValue[0], actualValue[0], diffs)
+		}
+	}
+}
+
+// sortedKeys returns the keys of an object in a stable order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+
+	"github.com/Treblle/treblle-go/v2"
+)
+
+// defaultMaxBodySize matches the largest response body the SDK sends
+const defaultMaxBodySize = 2 * 1024 * 1024
+
+var (
+	jwtPattern        = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	cardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// rawEvent is an undecoded event and the line it starts on
+type rawEvent struct {
+	line int
+	data json.RawMessage
+}
+
+// runValidate implements `treblle-go validate`
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	maxBody := fs.Int("max-body", defaultMaxBodySize, "Largest request or response body, in bytes")
+	fields := fs.String("fields", "", "Comma-separated AdditionalFieldsToMask to treat as sensitive")
+	printSchema := fs.Bool("schema", false, "Print the JSON Schema of the payload and exit")
+	configFile := fs.String("config", "", "Config file with the masking rules (default: $TREBLLE_CONFIG)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treblle-go validate [flags] <events.json|events.jsonl>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *printSchema {
+		schema, _ := json.MarshalIndent(eventSchema, "", "  ")
+		fmt.Println(string(schema))
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	treblle.Configure(treblle.Configuration{AdditionalFieldsToMask: splitFields(*fields), ConfigFile: *configFile})
+
+	events, invalid := 0, 0
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		raws, err := readRawEvents(data)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			invalid++
+			continue
+		}
+		for i, raw := range raws {
+			events++
+			problems := validateEvent(raw.data, *maxBody)
+			if len(problems) > 0 {
+				invalid++
+			}
+			for _, problem := range problems {
+				fmt.Printf("%s:%d: event %d: %s\n", path, raw.line, i+1, problem)
+			}
+		}
+	}
+
+	fmt.Printf("%d events checked, %d with problems\n", events, invalid)
+	if invalid > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readRawEvents splits a JSON event, JSON array of events or JSONL file into events
+func readRawEvents(data []byte) ([]rawEvent, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var events []json.RawMessage
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		raws := make([]rawEvent, len(events))
+		for i, event := range events {
+			raws[i] = rawEvent{line: 1, data: event}
+		}
+		return raws, nil
+	}
+
+	var raws []rawEvent
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	offset := 0
+	for {
+		var event json.RawMessage
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			return raws, nil
+		}
+		start := offset + len(data[offset:]) - len(bytes.TrimLeft(data[offset:], " \t\r\n"))
+		line := 1 + bytes.Count(data[:start], []byte("\n"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		raws = append(raws, rawEvent{line: line, data: event})
+		offset = int(decoder.InputOffset())
+	}
+}
+
+// validateEvent checks an event against the payload schema and lints its contents
+func validateEvent(data json.RawMessage, maxBody int) []string {
+	var event interface{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return []string{err.Error()}
+	}
+	problems := checkEvent(event)
+
+	var payload struct {
+		Data map[string]map[string]json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(data, &payload) != nil {
+		return problems
+	}
+	for _, section := range []string{"request", "response"} {
+		info := payload.Data[section]
+		prefix := "data." + section
+		if size := len(info["body"]); size > maxBody {
+			problems = append(problems, fmt.Sprintf("%s.body: %d bytes is larger than %d bytes", prefix, size, maxBody))
+		}
+		for _, field := range []string{"headers", "body", "query"} {
+			problems = append(problems, lintSensitive(prefix+"."+field, info[field])...)
+		}
+	}
+	return problems
+}
+
+// lintSensitive reports values that the SDK's masking would have masked and strings that look
+// like tokens or card numbers
+func lintSensitive(path string, raw json.RawMessage) []string {
+	var original interface{}
+	if len(raw) == 0 || json.Unmarshal(raw, &original) != nil {
+		return nil
+	}
+
+	var problems []string
+	masked := map[string]treblle.MaskedField{}
+	switch original.(type) {
+	case map[string]interface{}, []interface{}:
+		maskedRaw, fields, err := treblle.MaskJSON(raw)
+		if err != nil {
+			return nil
+		}
+		var maskedValue interface{}
+		json.Unmarshal(maskedRaw, &maskedValue)
+		for _, field := range fields {
+			masked[field.Path] = field
+		}
+		compareMasked("", original, maskedValue, masked, func(p string, field treblle.MaskedField) {
+			problems = append(problems, fmt.Sprintf("%s: unmasked sensitive value (%s, %s)", joinMaskedPath(path, p), field.Field, field.Rule))
+		})
+	}
+
+	walkStrings("", original, masked, func(p, value string) {
+		switch {
+		case jwtPattern.MatchString(value):
+			problems = append(problems, fmt.Sprintf("%s: value looks like a JWT", joinMaskedPath(path, p)))
+		case cardNumberPattern.MatchString(value) && luhnValid(cardNumberPattern.FindString(value)):
+			problems = append(problems, fmt.Sprintf("%s: value looks like a card number", joinMaskedPath(path, p)))
+		}
+	})
+	return problems
+}
+
+// compareMasked calls report for every masked path whose original value was not already masked
+func compareMasked(path string, original, masked interface{}, fields map[string]treblle.MaskedField, report func(string, treblle.MaskedField)) {
+	if field, ok := fields[path]; ok && path != "" {
+		if !reflect.DeepEqual(original, masked) {
+			report(path, field)
+		}
+		return
+	}
+
+	switch original := original.(type) {
+	case map[string]interface{}:
+		maskedObject, _ := masked.(map[string]interface{})
+		for _, key := range sortedKeys(original) {
+			compareMasked(joinPath(path, key), original[key], maskedObject[key], fields, report)
+		}
+	case []interface{}:
+		maskedItems, _ := masked.([]interface{})
+		for i, item := range original {
+			if i < len(maskedItems) {
+				compareMasked(fmt.Sprintf("%s[%d]", path, i), item, maskedItems[i], fields, report)
+			}
+		}
+	}
+}
+
+// walkStrings calls visit for every string value outside the masked paths
+func walkStrings(path string, value interface{}, masked map[string]treblle.MaskedField, visit func(string, string)) {
+	if _, ok := masked[path]; ok && path != "" {
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		visit(path, value)
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			walkStrings(joinPath(path, key), value[key], masked, visit)
+		}
+	case []interface{}:
+		for i, item := range value {
+			walkStrings(fmt.Sprintf("%s[%d]", path, i), item, masked, visit)
+		}
+	}
+}
+
+// luhnValid reports whether the digits in number pass the Luhn checksum used by card numbers
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
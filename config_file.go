@@ -0,0 +1,283 @@
+package treblle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfiguration is the layout of a configuration file. Keys are the snake_case names of the
+// Configuration fields; durations are strings such as "5s".
+type fileConfiguration struct {
+	SDKToken                 string                     `json:"sdk_token" yaml:"sdk_token" toml:"sdk_token"`
+	APIKey                   string                     `json:"api_key" yaml:"api_key" toml:"api_key"`
+	Endpoint                 string                     `json:"endpoint" yaml:"endpoint" toml:"endpoint"`
+	AdditionalFieldsToMask   []string                   `json:"additional_fields_to_mask" yaml:"additional_fields_to_mask" toml:"additional_fields_to_mask"`
+	DefaultFieldsToMask      []string                   `json:"default_fields_to_mask" yaml:"default_fields_to_mask" toml:"default_fields_to_mask"`
+	BatchErrorEnabled        bool                       `json:"batch_error_enabled" yaml:"batch_error_enabled" toml:"batch_error_enabled"`
+	BatchErrorSize           int                        `json:"batch_error_size" yaml:"batch_error_size" toml:"batch_error_size"`
+	BatchFlushInterval       fileDuration               `json:"batch_flush_interval" yaml:"batch_flush_interval" toml:"batch_flush_interval"`
+	BatchErrorRateLimit      int                        `json:"batch_error_rate_limit" yaml:"batch_error_rate_limit" toml:"batch_error_rate_limit"`
+	BatchErrorRateWindow     fileDuration               `json:"batch_error_rate_window" yaml:"batch_error_rate_window" toml:"batch_error_rate_window"`
+	BatchErrorMirrorRequests bool                       `json:"batch_error_mirror_requests" yaml:"batch_error_mirror_requests" toml:"batch_error_mirror_requests"`
+	SDKName                  string                     `json:"sdk_name" yaml:"sdk_name" toml:"sdk_name"`
+	SDKVersion               float64                    `json:"sdk_version" yaml:"sdk_version" toml:"sdk_version"`
+	AsyncProcessingEnabled   bool                       `json:"async_processing_enabled" yaml:"async_processing_enabled" toml:"async_processing_enabled"`
+	MaxConcurrentProcessing  int                        `json:"max_concurrent_processing" yaml:"max_concurrent_processing" toml:"max_concurrent_processing"`
+	AsyncShutdownTimeout     fileDuration               `json:"async_shutdown_timeout" yaml:"async_shutdown_timeout" toml:"async_shutdown_timeout"`
+	AsyncQueueSize           int                        `json:"async_queue_size" yaml:"async_queue_size" toml:"async_queue_size"`
+	AsyncOverflowPolicy      string                     `json:"async_overflow_policy" yaml:"async_overflow_policy" toml:"async_overflow_policy"`
+	AsyncEnqueueTimeout      fileDuration               `json:"async_enqueue_timeout" yaml:"async_enqueue_timeout" toml:"async_enqueue_timeout"`
+	IgnoredEnvironments      []string                   `json:"ignored_environments" yaml:"ignored_environments" toml:"ignored_environments"`
+	Debug                    bool                       `json:"debug" yaml:"debug" toml:"debug"`
+	GraphQLEnabled           bool                       `json:"graphql_enabled" yaml:"graphql_enabled" toml:"graphql_enabled"`
+	GraphQLPaths             []string                   `json:"graphql_paths" yaml:"graphql_paths" toml:"graphql_paths"`
+	JSONRPCEnabled           bool                       `json:"jsonrpc_enabled" yaml:"jsonrpc_enabled" toml:"jsonrpc_enabled"`
+	JSONRPCPaths             []string                   `json:"jsonrpc_paths" yaml:"jsonrpc_paths" toml:"jsonrpc_paths"`
+	RouteRules               []fileRouteRule            `json:"route_rules" yaml:"route_rules" toml:"route_rules"`
+	RoutePrefixRules         map[string][]fileRouteRule `json:"route_prefix_rules" yaml:"route_prefix_rules" toml:"route_prefix_rules"`
+	RouteCardinalityLimit    int                        `json:"route_cardinality_limit" yaml:"route_cardinality_limit" toml:"route_cardinality_limit"`
+	RouteCardinalityPrefixes int                        `json:"route_cardinality_prefixes" yaml:"route_cardinality_prefixes" toml:"route_cardinality_prefixes"`
+	RepanicEnabled           bool                       `json:"repanic_enabled" yaml:"repanic_enabled" toml:"repanic_enabled"`
+	StatusErrorsEnabled      bool                       `json:"status_errors_enabled" yaml:"status_errors_enabled" toml:"status_errors_enabled"`
+	StatusErrorTypes         map[string]string          `json:"status_error_types" yaml:"status_error_types" toml:"status_error_types"`
+	ErrorDetailsEnabled      bool                       `json:"error_details_enabled" yaml:"error_details_enabled" toml:"error_details_enabled"`
+	StackTraceDepth          int                        `json:"stack_trace_depth" yaml:"stack_trace_depth" toml:"stack_trace_depth"`
+	StackTraceModulePath     string                     `json:"stack_trace_module_path" yaml:"stack_trace_module_path" toml:"stack_trace_module_path"`
+	SampleRate               float64                    `json:"sample_rate" yaml:"sample_rate" toml:"sample_rate"`
+	IgnoredRoutes            []string                   `json:"ignored_routes" yaml:"ignored_routes" toml:"ignored_routes"`
+	Exporters                []fileExporter             `json:"exporters" yaml:"exporters" toml:"exporters"`
+	DisableTreblle           bool                       `json:"disable_treblle" yaml:"disable_treblle" toml:"disable_treblle"`
+}
+
+// fileRouteRule is a route rule in a configuration file. Without a pattern, the built-in rule
+// with the same placeholder is used, e.g. "uuid".
+type fileRouteRule struct {
+	Placeholder string `json:"placeholder" yaml:"placeholder" toml:"placeholder"`
+	Pattern     string `json:"pattern" yaml:"pattern" toml:"pattern"`
+}
+
+// fileExporter is an exporter in a configuration file; "file" is the only type
+type fileExporter struct {
+	Type string `json:"type" yaml:"type" toml:"type"`
+	Path string `json:"path" yaml:"path" toml:"path"`
+}
+
+// fileDuration is a duration written as a string such as "100ms" or "1m30s"
+type fileDuration time.Duration
+
+// UnmarshalText parses the duration
+func (d *fileDuration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = fileDuration(value)
+	return nil
+}
+
+// builtinRouteRules are the built-in rules by placeholder
+var builtinRouteRules = map[string]RouteRule{
+	"id":       NumericIDRule,
+	"uuid":     UUIDRule,
+	"ulid":     ULIDRule,
+	"objectid": ObjectIDRule,
+	"hash":     HexHashRule,
+	"date":     DateRule,
+	"email":    EmailRule,
+	"slug":     SlugRule,
+}
+
+// envBackedFields are the Configuration fields that can also be set with an environment
+// variable; their resolvers put the environment between code and the file
+var envBackedFields = map[string]bool{
+	"SDK_TOKEN":              true,
+	"API_KEY":                true,
+	"Endpoint":               true,
+	"SDKName":                true,
+	"SDKVersion":             true,
+	"AdditionalFieldsToMask": true,
+	"IgnoredEnvironments":    true,
+	"ConfigFile":             true,
+}
+
+// LoadConfig reads a YAML (.yaml, .yml), JSON (.json) or TOML (.toml) configuration file. The
+// result only holds the values set in the file. To apply it beneath environment variables and
+// code, set Configuration.ConfigFile instead of passing the result to Configure, which would
+// treat every value as set in code.
+//
+// PanicResponse, predicate route rules and exporters other than files can only be set in code.
+func LoadConfig(path string) (Configuration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	var file fileConfiguration
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return Configuration{}, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return Configuration{}, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return Configuration{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return Configuration{}, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return Configuration{}, fmt.Errorf("%s: unsupported config file extension %q (use .yaml, .yml, .json or .toml)", path, ext)
+	}
+
+	config, err := file.configuration()
+	if err != nil {
+		return Configuration{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// configuration converts the file layout to a Configuration
+func (f fileConfiguration) configuration() (Configuration, error) {
+	config := Configuration{
+		SDK_TOKEN:                f.SDKToken,
+		API_KEY:                  f.APIKey,
+		Endpoint:                 f.Endpoint,
+		AdditionalFieldsToMask:   f.AdditionalFieldsToMask,
+		DefaultFieldsToMask:      f.DefaultFieldsToMask,
+		BatchErrorEnabled:        f.BatchErrorEnabled,
+		BatchErrorSize:           f.BatchErrorSize,
+		BatchFlushInterval:       time.Duration(f.BatchFlushInterval),
+		BatchErrorRateLimit:      f.BatchErrorRateLimit,
+		BatchErrorRateWindow:     time.Duration(f.BatchErrorRateWindow),
+		BatchErrorMirrorRequests: f.BatchErrorMirrorRequests,
+		SDKName:                  f.SDKName,
+		SDKVersion:               f.SDKVersion,
+		AsyncProcessingEnabled:   f.AsyncProcessingEnabled,
+		MaxConcurrentProcessing:  f.MaxConcurrentProcessing,
+		AsyncShutdownTimeout:     time.Duration(f.AsyncShutdownTimeout),
+		AsyncQueueSize:           f.AsyncQueueSize,
+		AsyncOverflowPolicy:      OverflowPolicy(f.AsyncOverflowPolicy),
+		AsyncEnqueueTimeout:      time.Duration(f.AsyncEnqueueTimeout),
+		IgnoredEnvironments:      f.IgnoredEnvironments,
+		Debug:                    f.Debug,
+		GraphQLEnabled:           f.GraphQLEnabled,
+		GraphQLPaths:             f.GraphQLPaths,
+		JSONRPCEnabled:           f.JSONRPCEnabled,
+		JSONRPCPaths:             f.JSONRPCPaths,
+		RouteCardinalityLimit:    f.RouteCardinalityLimit,
+		RouteCardinalityPrefixes: f.RouteCardinalityPrefixes,
+		RepanicEnabled:           f.RepanicEnabled,
+		StatusErrorsEnabled:      f.StatusErrorsEnabled,
+		ErrorDetailsEnabled:      f.ErrorDetailsEnabled,
+		StackTraceDepth:          f.StackTraceDepth,
+		StackTraceModulePath:     f.StackTraceModulePath,
+		SampleRate:               f.SampleRate,
+		IgnoredRoutes:            f.IgnoredRoutes,
+		DisableTreblle:           f.DisableTreblle,
+	}
+
+	var err error
+	if config.RouteRules, err = routeRulesFromFile(f.RouteRules); err != nil {
+		return Configuration{}, err
+	}
+	if len(f.RoutePrefixRules) > 0 {
+		config.RoutePrefixRules = make(map[string][]RouteRule, len(f.RoutePrefixRules))
+		for prefix, rules := range f.RoutePrefixRules {
+			if config.RoutePrefixRules[prefix], err = routeRulesFromFile(rules); err != nil {
+				return Configuration{}, err
+			}
+		}
+	}
+
+	if len(f.StatusErrorTypes) > 0 {
+		config.StatusErrorTypes = make(map[int]ErrorType, len(f.StatusErrorTypes))
+		for status, errType := range f.StatusErrorTypes {
+			code, err := strconv.Atoi(status)
+			if err != nil {
+				return Configuration{}, fmt.Errorf("status_error_types: invalid status code %q", status)
+			}
+			config.StatusErrorTypes[code] = ErrorType(errType)
+		}
+	}
+
+	for i, exporter := range f.Exporters {
+		if exporter.Type != "file" {
+			return Configuration{}, fmt.Errorf("exporters[%d]: unsupported type %q (use \"file\")", i, exporter.Type)
+		}
+		if exporter.Path == "" {
+			return Configuration{}, fmt.Errorf("exporters[%d]: path is required", i)
+		}
+		config.Exporters = append(config.Exporters, fileExporterFor(exporter.Path))
+	}
+
+	return config, nil
+}
+
+// routeRulesFromFile converts route rules from a configuration file
+func routeRulesFromFile(rules []fileRouteRule) ([]RouteRule, error) {
+	var converted []RouteRule
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			builtin, ok := builtinRouteRules[rule.Placeholder]
+			if !ok {
+				return nil, fmt.Errorf("route rule %q: pattern is required for a placeholder without a built-in rule", rule.Placeholder)
+			}
+			converted = append(converted, builtin)
+			continue
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("route rule %q: %w", rule.Placeholder, err)
+		}
+		converted = append(converted, RouteRule{Placeholder: rule.Placeholder, Pattern: pattern})
+	}
+	return converted, nil
+}
+
+// loadConfigFile loads the configuration file named in code or by TREBLLE_CONFIG, if any
+func loadConfigFile(config Configuration) (Configuration, error) {
+	path, _ := resolveConfigFile(config)
+	if path == "" {
+		return Configuration{}, nil
+	}
+	return LoadConfig(path)
+}
+
+// mergeConfiguration fills the fields left unset in code with the values from the file. Fields
+// that can also come from the environment are left to their resolvers. A field set in code to its
+// zero value cannot be told apart from an unset one, so the file value wins in that case.
+func mergeConfiguration(config, file Configuration) Configuration {
+	code := reflect.ValueOf(&config).Elem()
+	loaded := reflect.ValueOf(file)
+	for i := 0; i < code.NumField(); i++ {
+		if envBackedFields[code.Type().Field(i).Name] {
+			continue
+		}
+		if field := code.Field(i); field.IsZero() {
+			field.Set(loaded.Field(i))
+		}
+	}
+	return config
+}
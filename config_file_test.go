@@ -0,0 +1,115 @@
+package treblle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFormats(t *testing.T) {
+	for _, name := range []string{"treblle.yaml", "treblle.json", "treblle.toml"} {
+		t.Run(name, func(t *testing.T) {
+			config, err := LoadConfig(filepath.Join("testdata", "config", name))
+			require.NoError(t, err)
+
+			assert.Equal(t, "file-sdk-token", config.SDK_TOKEN)
+			assert.Equal(t, "file-api-key", config.API_KEY)
+			assert.Equal(t, "http://localhost:8787", config.Endpoint)
+			assert.Equal(t, "go-file", config.SDKName)
+			assert.Equal(t, []string{"pin", "otp"}, config.AdditionalFieldsToMask)
+			assert.Equal(t, []string{"local"}, config.IgnoredEnvironments)
+			assert.True(t, config.Debug)
+			assert.True(t, config.AsyncProcessingEnabled)
+			assert.Equal(t, 500, config.AsyncQueueSize)
+			assert.Equal(t, OverflowDropOldest, config.AsyncOverflowPolicy)
+			assert.Equal(t, 250*time.Millisecond, config.AsyncEnqueueTimeout)
+			assert.Equal(t, 90*time.Second, config.BatchFlushInterval)
+			assert.Equal(t, map[int]ErrorType{409: ValidationError}, config.StatusErrorTypes)
+			assert.Equal(t, 0.25, config.SampleRate)
+			assert.Equal(t, []string{"/health", "/internal/*"}, config.IgnoredRoutes)
+
+			require.Len(t, config.RouteRules, 2)
+			assert.Equal(t, "uuid", config.RouteRules[0].Placeholder)
+			assert.True(t, config.RouteRules[0].matches("123e4567-e89b-12d3-a456-426614174000"), "built-in rule by placeholder")
+			assert.True(t, config.RouteRules[1].matches("SKU-42"))
+			require.Len(t, config.RoutePrefixRules["/files"], 1)
+			assert.True(t, config.RoutePrefixRules["/files"][0].matches("7"))
+
+			require.Len(t, config.Exporters, 1)
+			assert.Equal(t, "events.jsonl", config.Exporters[0].(*FileExporter).path)
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+		return path
+	}
+
+	cases := map[string]string{
+		write("typo.yaml", "sdk_tokn: abc\n"):                          "sdk_tokn",
+		write("typo.json", `{"sample_rat": 0.5}`):                      "sample_rat",
+		write("typo.toml", "debg = true\n"):                            "debg",
+		write("duration.yaml", "async_shutdown_timeout: soon\n"):       "soon",
+		write("rule.yaml", "route_rules: [{placeholder: sku}]\n"):      "pattern is required",
+		write("exporter.json", `{"exporters": [{"type": "kafka"}]}`):   `unsupported type "kafka"`,
+		write("status.toml", "[status_error_types]\nteapot = \"X\"\n"): `invalid status code "teapot"`,
+		write("treblle.ini", "debug=true\n"):                           "unsupported config file extension",
+	}
+	for path, message := range cases {
+		_, err := LoadConfig(path)
+		if assert.Error(t, err, path) {
+			assert.Contains(t, err.Error(), message)
+			assert.Contains(t, err.Error(), path, "errors name the file")
+		}
+	}
+
+	config, err := LoadConfig(write("empty.yaml", ""))
+	assert.NoError(t, err, "an empty file is an empty configuration")
+	assert.Equal(t, Configuration{}, config)
+}
+
+func TestConfigurePrecedence(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	// defaults < file < env < code
+	t.Setenv("TREBLLE_CONFIG", filepath.Join("testdata", "config", "treblle.yaml"))
+	t.Setenv("TREBLLE_SDK_NAME", "go-env")
+	t.Setenv("TREBLLE_API_KEY", "env-api-key")
+
+	config := Configuration{SDK_TOKEN: "code-sdk-token", AsyncQueueSize: 50}
+	Configure(config)
+
+	assert.Equal(t, "code-sdk-token", Config.APIKey, "code beats the file")
+	assert.Equal(t, "env-api-key", Config.ProjectID, "env beats the file")
+	assert.Equal(t, "go-env", Config.SDKName, "env beats the file")
+	assert.Equal(t, "http://localhost:8787", Config.Endpoint, "file beats the default")
+	assert.Equal(t, 50, Config.AsyncQueueSize, "code beats the file")
+	assert.Equal(t, OverflowDropOldest, Config.AsyncOverflowPolicy)
+	assert.Equal(t, 2.0, Config.SDKVersion, "defaults apply to settings set nowhere")
+	assert.Equal(t, 0.25, Config.SampleRate)
+	assert.True(t, Config.FieldsMap["otp"])
+
+	values := DescribeConfiguration(config)
+	assert.Equal(t, ConfigValue{Name: "ConfigFile", Value: filepath.Join("testdata", "config", "treblle.yaml"), Source: SourceEnv, EnvVar: "TREBLLE_CONFIG"}, describedValue(values, "ConfigFile"))
+	assert.Equal(t, SourceCode, describedValue(values, "SDK_TOKEN").Source)
+	assert.Equal(t, ConfigValue{Name: "API_KEY", Value: "****-key", Source: SourceEnv, EnvVar: "TREBLLE_API_KEY"}, describedValue(values, "API_KEY"))
+	assert.Equal(t, ConfigValue{Name: "Endpoint", Value: "http://localhost:8787", Source: SourceFile}, describedValue(values, "Endpoint"))
+	assert.Equal(t, ConfigValue{Name: "AdditionalFieldsToMask", Value: "pin,otp", Source: SourceFile}, describedValue(values, "AdditionalFieldsToMask"))
+
+	// The environment beats the file, and code beats the environment
+	t.Setenv("TREBLLE_MASKED_FIELDS", "cvv")
+	Configure(Configuration{})
+	Configure(Configuration{})
+	assert.Equal(t, []string{"cvv"}, Config.AdditionalFieldsToMask, "configuring again does not add the fields twice")
+	Configure(Configuration{AdditionalFieldsToMask: []string{"iban"}})
+	assert.Equal(t, []string{"iban"}, Config.AdditionalFieldsToMask)
+}
@@ -0,0 +1,236 @@
+package treblle
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigSource identifies where a configuration value came from
+type ConfigSource string
+
+const (
+	SourceDefault ConfigSource = "default"
+	SourceFile    ConfigSource = "file"
+	SourceEnv     ConfigSource = "env"
+	SourceCode    ConfigSource = "code"
+)
+
+// ConfigValue is a resolved configuration value and its source
+type ConfigValue struct {
+	Name   string
+	Value  string
+	Source ConfigSource
+	EnvVar string // Environment variable the value was read from, if any
+}
+
+// environmentVariables are read, in order, to determine the current environment
+var environmentVariables = []string{"GO_ENV", "ENV", "ENVIRONMENT", "APP_ENV"}
+
+// defaultIgnoredEnvironments are ignored when neither code, TREBLLE_IGNORED_ENV nor a config file set them
+var defaultIgnoredEnvironments = []string{"dev", "test", "testing"}
+
+// knownEnvVars are the TREBLLE_* variables read by Configure
+var knownEnvVars = map[string]bool{
+	"TREBLLE_CONFIG":        true,
+	"TREBLLE_SDK_TOKEN":     true,
+	"TREBLLE_API_KEY":       true,
+	"TREBLLE_ENDPOINT":      true,
+	"TREBLLE_SDK_NAME":      true,
+	"TREBLLE_SDK_VERSION":   true,
+	"TREBLLE_MASKED_FIELDS": true,
+	"TREBLLE_IGNORED_ENV":   true,
+}
+
+// misleadingEnvVars are TREBLLE_* variables that look like configuration but are not read by
+// Configure, with what to use instead
+var misleadingEnvVars = map[string]string{
+	"TREBLLE_IGNORED_ENVIRONMENTS": "TREBLLE_IGNORED_ENV",
+	"TREBLLE_CONFIG_FILE":          "TREBLLE_CONFIG",
+}
+
+// Settings are resolved in the order code, environment, config file, default; the first one that
+// is set wins.
+
+// resolveString returns the first of the code value, the environment variable and the file value
+// that is set
+func resolveString(code, envVar, file string) (string, ConfigSource) {
+	if code != "" {
+		return code, SourceCode
+	}
+	if value := os.Getenv(envVar); value != "" {
+		return value, SourceEnv
+	}
+	if file != "" {
+		return file, SourceFile
+	}
+	return "", SourceDefault
+}
+
+// resolveConfigFile returns the path of the config file Configure loads
+func resolveConfigFile(config Configuration) (string, ConfigSource) {
+	return resolveString(config.ConfigFile, "TREBLLE_CONFIG", "")
+}
+
+// resolveSDKToken returns the SDK token Configure uses
+func resolveSDKToken(config, file Configuration) (string, ConfigSource) {
+	return resolveString(config.SDK_TOKEN, "TREBLLE_SDK_TOKEN", file.SDK_TOKEN)
+}
+
+// resolveAPIKey returns the API key Configure uses
+func resolveAPIKey(config, file Configuration) (string, ConfigSource) {
+	return resolveString(config.API_KEY, "TREBLLE_API_KEY", file.API_KEY)
+}
+
+// resolveEndpoint returns the custom endpoint Configure uses
+func resolveEndpoint(config, file Configuration) (string, ConfigSource) {
+	return resolveString(config.Endpoint, "TREBLLE_ENDPOINT", file.Endpoint)
+}
+
+// resolveSDKName returns the SDK name Configure uses
+func resolveSDKName(config, file Configuration) (string, ConfigSource) {
+	if value, source := resolveString(config.SDKName, "TREBLLE_SDK_NAME", file.SDKName); source != SourceDefault {
+		return value, source
+	}
+	return SDKName, SourceDefault
+}
+
+// resolveSDKVersion returns the SDK version Configure uses
+func resolveSDKVersion(config, file Configuration) (float64, ConfigSource) {
+	if config.SDKVersion != 0 {
+		return config.SDKVersion, SourceCode
+	}
+	if value, err := strconv.ParseFloat(os.Getenv("TREBLLE_SDK_VERSION"), 64); err == nil {
+		return value, SourceEnv
+	}
+	if file.SDKVersion != 0 {
+		return file.SDKVersion, SourceFile
+	}
+	return SDKVersion, SourceDefault
+}
+
+// resolveAdditionalFieldsToMask returns the additional masked fields
+func resolveAdditionalFieldsToMask(config, file Configuration) ([]string, ConfigSource) {
+	return resolveList(config.AdditionalFieldsToMask, getEnvMaskedFields(), file.AdditionalFieldsToMask, nil)
+}
+
+// resolveIgnoredEnvironments returns the environments where Treblle does not capture requests
+func resolveIgnoredEnvironments(config, file Configuration) ([]string, ConfigSource) {
+	return resolveList(config.IgnoredEnvironments, getEnvAsSlice("TREBLLE_IGNORED_ENV", nil), file.IgnoredEnvironments, defaultIgnoredEnvironments)
+}
+
+// resolveList returns the first of the code, environment and file lists that is not empty
+func resolveList(code, env, file, defaults []string) ([]string, ConfigSource) {
+	switch {
+	case len(code) > 0:
+		return code, SourceCode
+	case len(env) > 0:
+		return env, SourceEnv
+	case len(file) > 0:
+		return file, SourceFile
+	default:
+		return defaults, SourceDefault
+	}
+}
+
+// currentEnvironment returns the name of the running environment and the variable it was read from
+func currentEnvironment() (string, string) {
+	for _, name := range environmentVariables {
+		if value := os.Getenv(name); value != "" {
+			return value, name
+		}
+	}
+	return "", ""
+}
+
+// DescribeConfiguration reports the value Configure would use for each setting that can come from
+// the environment, a config file or a default, and where that value comes from. A config file
+// that cannot be loaded is treated as empty; use LoadConfig to check it.
+func DescribeConfiguration(config Configuration) []ConfigValue {
+	file, _ := loadConfigFile(config)
+
+	path, source := resolveConfigFile(config)
+	values := []ConfigValue{{Name: "ConfigFile", Value: path, Source: source, EnvVar: envVarFor(source, "TREBLLE_CONFIG")}}
+
+	token, source := resolveSDKToken(config, file)
+	values = append(values, ConfigValue{Name: "SDK_TOKEN", Value: maskString(token), Source: source, EnvVar: envVarFor(source, "TREBLLE_SDK_TOKEN")})
+
+	apiKey, source := resolveAPIKey(config, file)
+	values = append(values, ConfigValue{Name: "API_KEY", Value: maskString(apiKey), Source: source, EnvVar: envVarFor(source, "TREBLLE_API_KEY")})
+
+	endpoint, source := resolveEndpoint(config, file)
+	if source == SourceDefault {
+		endpoint = strings.Join(defaultEndpoints, ", ")
+	}
+	values = append(values, ConfigValue{Name: "Endpoint", Value: endpoint, Source: source, EnvVar: envVarFor(source, "TREBLLE_ENDPOINT")})
+
+	name, source := resolveSDKName(config, file)
+	values = append(values, ConfigValue{Name: "SDKName", Value: name, Source: source, EnvVar: envVarFor(source, "TREBLLE_SDK_NAME")})
+
+	version, source := resolveSDKVersion(config, file)
+	values = append(values, ConfigValue{Name: "SDKVersion", Value: strconv.FormatFloat(version, 'f', -1, 64), Source: source, EnvVar: envVarFor(source, "TREBLLE_SDK_VERSION")})
+
+	defaultFields, source := resolveList(config.DefaultFieldsToMask, nil, file.DefaultFieldsToMask, getDefaultFieldsToMask())
+	values = append(values, ConfigValue{Name: "DefaultFieldsToMask", Value: strings.Join(defaultFields, ","), Source: source})
+
+	fields, source := resolveAdditionalFieldsToMask(config, file)
+	values = append(values, ConfigValue{Name: "AdditionalFieldsToMask", Value: strings.Join(fields, ","), Source: source, EnvVar: envVarFor(source, "TREBLLE_MASKED_FIELDS")})
+
+	ignored, source := resolveIgnoredEnvironments(config, file)
+	values = append(values, ConfigValue{Name: "IgnoredEnvironments", Value: strings.Join(ignored, ","), Source: source, EnvVar: envVarFor(source, "TREBLLE_IGNORED_ENV")})
+
+	environment, envVar := currentEnvironment()
+	current := ConfigValue{Name: "Environment", Value: environment, Source: SourceDefault}
+	if envVar != "" {
+		current.Source = SourceEnv
+		current.EnvVar = envVar
+	}
+	values = append(values, current)
+
+	return values
+}
+
+// envVarFor returns envVar if the value came from the environment
+func envVarFor(source ConfigSource, envVar string) string {
+	if source == SourceEnv {
+		return envVar
+	}
+	return ""
+}
+
+// EnvironmentWarnings reports TREBLLE_* environment variables that Configure does not read and
+// environment names that disagree with each other
+func EnvironmentWarnings() []string {
+	var warnings []string
+
+	var names []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, "TREBLLE_") && !knownEnvVars[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if replacement, ok := misleadingEnvVars[name]; ok {
+			warnings = append(warnings, fmt.Sprintf("%s is not read by Configure; use %s", name, replacement))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s is not a known Treblle setting", name))
+		}
+	}
+
+	if legacy, current := os.Getenv("TREBLLE_IGNORED_ENVIRONMENTS"), os.Getenv("TREBLLE_IGNORED_ENV"); legacy != "" && current != "" && legacy != current {
+		warnings = append(warnings, fmt.Sprintf("TREBLLE_IGNORED_ENVIRONMENTS=%q conflicts with TREBLLE_IGNORED_ENV=%q; only TREBLLE_IGNORED_ENV is used", legacy, current))
+	}
+
+	environment, envVar := currentEnvironment()
+	for _, name := range environmentVariables {
+		if value := os.Getenv(name); value != "" && value != environment {
+			warnings = append(warnings, fmt.Sprintf("%s=%q conflicts with %s=%q; %s is used", name, value, envVar, environment, envVar))
+		}
+	}
+
+	return warnings
+}
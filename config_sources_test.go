@@ -0,0 +1,80 @@
+package treblle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clearConfigEnv unsets the environment variables read by Configure for the duration of a test
+func clearConfigEnv(t *testing.T) {
+	for name := range knownEnvVars {
+		t.Setenv(name, "")
+	}
+	for _, name := range environmentVariables {
+		t.Setenv(name, "")
+	}
+}
+
+func describedValue(values []ConfigValue, name string) ConfigValue {
+	for _, value := range values {
+		if value.Name == name {
+			return value
+		}
+	}
+	return ConfigValue{}
+}
+
+func TestDescribeConfigurationSources(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("TREBLLE_SDK_VERSION", "2.5")
+	t.Setenv("TREBLLE_MASKED_FIELDS", "pin,otp")
+	t.Setenv("ENV", "staging")
+
+	values := DescribeConfiguration(Configuration{
+		SDK_TOKEN:           "sdk-token-1234",
+		SDKName:             "go-custom",
+		IgnoredEnvironments: []string{"staging"},
+	})
+
+	assert.Equal(t, ConfigValue{Name: "SDK_TOKEN", Value: "****1234", Source: SourceCode}, describedValue(values, "SDK_TOKEN"))
+	assert.Equal(t, ConfigValue{Name: "API_KEY", Value: "Not Set", Source: SourceDefault}, describedValue(values, "API_KEY"))
+	assert.Equal(t, SourceDefault, describedValue(values, "Endpoint").Source)
+	assert.Equal(t, ConfigValue{Name: "SDKName", Value: "go-custom", Source: SourceCode}, describedValue(values, "SDKName"))
+	assert.Equal(t, ConfigValue{Name: "SDKVersion", Value: "2.5", Source: SourceEnv, EnvVar: "TREBLLE_SDK_VERSION"}, describedValue(values, "SDKVersion"))
+	assert.Equal(t, ConfigValue{Name: "AdditionalFieldsToMask", Value: "pin,otp", Source: SourceEnv, EnvVar: "TREBLLE_MASKED_FIELDS"}, describedValue(values, "AdditionalFieldsToMask"))
+	assert.Equal(t, ConfigValue{Name: "IgnoredEnvironments", Value: "staging", Source: SourceCode}, describedValue(values, "IgnoredEnvironments"))
+	assert.Equal(t, ConfigValue{Name: "Environment", Value: "staging", Source: SourceEnv, EnvVar: "ENV"}, describedValue(values, "Environment"))
+}
+
+func TestDescribeConfigurationMatchesConfigure(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	t.Setenv("TREBLLE_SDK_NAME", "go-env")
+	t.Setenv("TREBLLE_IGNORED_ENV", "qa,uat")
+
+	config := Configuration{SDKName: "go-code", SDKVersion: 3}
+	values := DescribeConfiguration(config)
+	Configure(config)
+
+	assert.Equal(t, Config.SDKName, describedValue(values, "SDKName").Value)
+	assert.Equal(t, "3", describedValue(values, "SDKVersion").Value)
+	assert.Equal(t, 3.0, Config.SDKVersion)
+	assert.Equal(t, []string{"qa", "uat"}, Config.IgnoredEnvironments)
+	assert.Equal(t, "qa,uat", describedValue(values, "IgnoredEnvironments").Value)
+}
+
+func TestEnvironmentWarnings(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("TREBLLE_IGNORED_ENV", "dev")
+	t.Setenv("TREBLLE_IGNORED_ENVIRONMENTS", "local")
+	t.Setenv("TREBLLE_MASK_FIELDS", "pin")
+	t.Setenv("GO_ENV", "production")
+	t.Setenv("APP_ENV", "dev")
+
+	warnings := EnvironmentWarnings()
+	assert.Contains(t, warnings, "TREBLLE_IGNORED_ENVIRONMENTS is not read by Configure; use TREBLLE_IGNORED_ENV")
+	assert.Contains(t, warnings, "TREBLLE_MASK_FIELDS is not a known Treblle setting")
+	assert.Contains(t, warnings, `TREBLLE_IGNORED_ENVIRONMENTS="local" conflicts with TREBLLE_IGNORED_ENV="dev"; only TREBLLE_IGNORED_ENV is used`)
+	assert.Contains(t, warnings, `APP_ENV="dev" conflicts with GO_ENV="production"; GO_ENV is used`)
+}
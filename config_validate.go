@@ -0,0 +1,234 @@
+package treblle
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigProblem is an invalid or contradictory setting
+type ConfigProblem struct {
+	Setting string // Configuration field or environment variable, e.g. "BatchErrorSize" or "TREBLLE_SDK_VERSION"
+	Message string // What is wrong, e.g. "must not be negative (got -1)"
+}
+
+// Error implements the error interface
+func (p ConfigProblem) Error() string {
+	return p.Setting + " " + p.Message
+}
+
+// ConfigError lists every problem found by Validate
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Error()
+	}
+	return fmt.Sprintf("treblle: invalid configuration: %s", strings.Join(messages, "; "))
+}
+
+// Unwrap returns the problems, so errors.As can match a ConfigProblem
+func (e *ConfigError) Unwrap() []error {
+	errs := make([]error, len(e.Problems))
+	for i, problem := range e.Problems {
+		errs[i] = problem
+	}
+	return errs
+}
+
+// Validate checks the configuration as Configure would resolve it, including the config file and
+// environment variables, and returns a *ConfigError listing every invalid or contradictory
+// setting, or nil. Deprecated or unknown environment variables are reported by
+// EnvironmentWarnings instead, as they do not stop the SDK from working.
+func (c Configuration) Validate() error {
+	file, err := loadConfigFile(c)
+	if problems := validateConfiguration(c, file, err); problems != nil {
+		return problems
+	}
+	return nil
+}
+
+// ConfigureE validates the configuration and applies it with Configure if it is valid. Otherwise
+// it returns a *ConfigError and leaves the current configuration unchanged. Environment warnings
+// are logged.
+func ConfigureE(config Configuration) error {
+	for _, warning := range EnvironmentWarnings() {
+		logWarning(warning)
+	}
+
+	file, err := loadConfigFile(config)
+	if problems := validateConfiguration(config, file, err); problems != nil {
+		return problems
+	}
+	configure(config, file)
+	return nil
+}
+
+// validateConfiguration checks the configuration given in code combined with the config file
+// and the environment
+func validateConfiguration(config, file Configuration, fileErr error) *ConfigError {
+	var problems []ConfigProblem
+	add := func(setting, format string, args ...interface{}) {
+		problems = append(problems, ConfigProblem{Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if fileErr != nil {
+		add("ConfigFile", "cannot be loaded: %v", fileErr)
+	}
+	config = mergeConfiguration(config, file)
+
+	// Credentials are only needed to send to Treblle
+	if !config.DisableTreblle {
+		if token, _ := resolveSDKToken(config, file); token == "" {
+			add("SDK_TOKEN", "is not set; events are rejected without it")
+		}
+		if apiKey, _ := resolveAPIKey(config, file); apiKey == "" {
+			add("API_KEY", "is not set; events cannot be assigned to a project")
+		}
+	} else if len(config.Exporters) == 0 {
+		add("DisableTreblle", "is set without Exporters; every event would be dropped")
+	}
+
+	if endpoint, _ := resolveEndpoint(config, file); endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("Endpoint", "is not an http or https URL (got %q)", endpoint)
+		}
+	}
+
+	if value := os.Getenv("TREBLLE_SDK_VERSION"); value != "" {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			add("TREBLLE_SDK_VERSION", "is not a number (got %q)", value)
+		}
+	}
+	if config.SDKVersion < 0 || file.SDKVersion < 0 {
+		add("SDKVersion", "must not be negative")
+	}
+
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"BatchErrorSize", config.BatchErrorSize},
+		{"BatchErrorRateLimit", config.BatchErrorRateLimit},
+		{"MaxConcurrentProcessing", config.MaxConcurrentProcessing},
+		{"AsyncQueueSize", config.AsyncQueueSize},
+		{"RouteCardinalityLimit", config.RouteCardinalityLimit},
+		{"RouteCardinalityPrefixes", config.RouteCardinalityPrefixes},
+		{"StackTraceDepth", config.StackTraceDepth},
+	} {
+		if setting.value < 0 {
+			add(setting.name, "must not be negative (got %d)", setting.value)
+		}
+	}
+	for _, setting := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"BatchFlushInterval", config.BatchFlushInterval},
+		{"BatchErrorRateWindow", config.BatchErrorRateWindow},
+		{"AsyncShutdownTimeout", config.AsyncShutdownTimeout},
+		{"AsyncEnqueueTimeout", config.AsyncEnqueueTimeout},
+	} {
+		if setting.value < 0 {
+			add(setting.name, "must not be negative (got %s)", setting.value)
+		}
+	}
+
+	switch config.AsyncOverflowPolicy {
+	case "", OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	default:
+		add("AsyncOverflowPolicy", "must be %q, %q or %q (got %q)", OverflowDropNewest, OverflowDropOldest, OverflowBlock, config.AsyncOverflowPolicy)
+	}
+
+	problems = append(problems, validateSettings(Settings{IgnoredRoutes: config.IgnoredRoutes, SampleRate: config.SampleRate})...)
+
+	// Settings that have no effect without the feature they belong to
+	if config.BatchErrorMirrorRequests && !config.BatchErrorEnabled {
+		add("BatchErrorMirrorRequests", "has no effect unless BatchErrorEnabled is set")
+	}
+	if len(config.GraphQLPaths) > 0 && !config.GraphQLEnabled {
+		add("GraphQLPaths", "has no effect unless GraphQLEnabled is set")
+	}
+	if len(config.JSONRPCPaths) > 0 && !config.JSONRPCEnabled {
+		add("JSONRPCPaths", "has no effect unless JSONRPCEnabled is set")
+	}
+	if config.RouteCardinalityPrefixes > 0 && config.RouteCardinalityLimit <= 0 {
+		add("RouteCardinalityPrefixes", "has no effect unless RouteCardinalityLimit is set")
+	}
+
+	validateRouteRules("RouteRules", config.RouteRules, add)
+	prefixes := make([]string, 0, len(config.RoutePrefixRules))
+	for prefix := range config.RoutePrefixRules {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			add("RoutePrefixRules", "prefix %q does not start with /", prefix)
+		}
+		validateRouteRules(fmt.Sprintf("RoutePrefixRules[%q]", prefix), config.RoutePrefixRules[prefix], add)
+	}
+
+	codes := make([]int, 0, len(config.StatusErrorTypes))
+	for code := range config.StatusErrorTypes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		if code < 100 || code > 599 {
+			add("StatusErrorTypes", "has an invalid status code %d", code)
+		}
+	}
+
+	for i, exporter := range config.Exporters {
+		if exporter == nil {
+			add(fmt.Sprintf("Exporters[%d]", i), "is nil")
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ConfigError{Problems: problems}
+}
+
+// validateSettings checks the settings that can change while the SDK is running
+func validateSettings(settings Settings) []ConfigProblem {
+	var problems []ConfigProblem
+	if settings.SampleRate < 0 || settings.SampleRate > 1 {
+		problems = append(problems, ConfigProblem{Setting: "SampleRate", Message: fmt.Sprintf("must be between 0 and 1 (got %g)", settings.SampleRate)})
+	}
+	for _, route := range settings.IgnoredRoutes {
+		if !strings.HasPrefix(route, "/") {
+			problems = append(problems, ConfigProblem{Setting: "IgnoredRoutes", Message: fmt.Sprintf("entry %q does not start with /", route)})
+		}
+	}
+	return problems
+}
+
+// validateRouteRules checks that every rule has a placeholder and something to match
+func validateRouteRules(setting string, rules []RouteRule, add func(setting, format string, args ...interface{})) {
+	for i, rule := range rules {
+		name := fmt.Sprintf("%s[%d]", setting, i)
+		if rule.Placeholder == "" {
+			add(name, "has no placeholder")
+		}
+		if rule.Pattern == nil && rule.Match == nil {
+			add(name, "has neither a Pattern nor a Match function")
+		}
+	}
+}
+
+// logWarning reports a configuration problem that does not stop the SDK from working
+func logWarning(warning string) {
+	log.Printf("treblle: warning: %s", warning)
+}
@@ -0,0 +1,97 @@
+package treblle
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("TREBLLE_SDK_VERSION", "2.x")
+
+	err := Configuration{
+		Endpoint:                 "localhost:8787",
+		BatchErrorSize:           -1,
+		AsyncShutdownTimeout:     -time.Second,
+		AsyncOverflowPolicy:      "drop-all",
+		SampleRate:               1.5,
+		IgnoredRoutes:            []string{"health"},
+		BatchErrorMirrorRequests: true,
+		GraphQLPaths:             []string{"/gql"},
+		RouteRules:               []RouteRule{{Placeholder: "id"}},
+		StatusErrorTypes:         map[int]ErrorType{700: ServerError},
+	}.Validate()
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Equal(t, []ConfigProblem{
+		{Setting: "SDK_TOKEN", Message: "is not set; events are rejected without it"},
+		{Setting: "API_KEY", Message: "is not set; events cannot be assigned to a project"},
+		{Setting: "Endpoint", Message: `is not an http or https URL (got "localhost:8787")`},
+		{Setting: "TREBLLE_SDK_VERSION", Message: `is not a number (got "2.x")`},
+		{Setting: "BatchErrorSize", Message: "must not be negative (got -1)"},
+		{Setting: "AsyncShutdownTimeout", Message: "must not be negative (got -1s)"},
+		{Setting: "AsyncOverflowPolicy", Message: `must be "drop-newest", "drop-oldest" or "block" (got "drop-all")`},
+		{Setting: "SampleRate", Message: "must be between 0 and 1 (got 1.5)"},
+		{Setting: "IgnoredRoutes", Message: `entry "health" does not start with /`},
+		{Setting: "BatchErrorMirrorRequests", Message: "has no effect unless BatchErrorEnabled is set"},
+		{Setting: "GraphQLPaths", Message: "has no effect unless GraphQLEnabled is set"},
+		{Setting: "RouteRules[0]", Message: "has neither a Pattern nor a Match function"},
+		{Setting: "StatusErrorTypes", Message: "has an invalid status code 700"},
+	}, configErr.Problems)
+
+	var problem ConfigProblem
+	require.True(t, errors.As(err, &problem), "problems are unwrapped")
+	assert.Contains(t, err.Error(), "treblle: invalid configuration: SDK_TOKEN is not set")
+}
+
+func TestValidateResolvesEnvironmentAndFile(t *testing.T) {
+	clearConfigEnv(t)
+
+	valid := Configuration{SDK_TOKEN: "sdk-token", API_KEY: "api-key", Endpoint: "https://collector.example.com"}
+	assert.NoError(t, valid.Validate())
+
+	t.Setenv("TREBLLE_API_KEY", "env-api-key")
+	assert.NoError(t, Configuration{SDK_TOKEN: "sdk-token"}.Validate(), "credentials can come from the environment")
+
+	assert.EqualError(t, Configuration{DisableTreblle: true}.Validate(),
+		"treblle: invalid configuration: DisableTreblle is set without Exporters; every event would be dropped")
+
+	path := filepath.Join(t.TempDir(), "treblle.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"sdk_token": "file-token", "sample_rate": -0.5}`), 0o644))
+	assert.EqualError(t, Configuration{ConfigFile: path}.Validate(),
+		"treblle: invalid configuration: SampleRate must be between 0 and 1 (got -0.5)")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sdk_token": 42}`), 0o644))
+	err := Configuration{ConfigFile: path}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ConfigFile cannot be loaded: "+path)
+}
+
+func TestConfigureE(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	Config.APIKey = "previous-sdk-token"
+	err := ConfigureE(Configuration{SDK_TOKEN: "new-sdk-token", API_KEY: "api-key", BatchErrorSize: -5})
+	assert.EqualError(t, err, "treblle: invalid configuration: BatchErrorSize must not be negative (got -5)")
+	assert.Equal(t, "previous-sdk-token", Config.APIKey, "an invalid configuration is not applied")
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	t.Setenv("TREBLLE_IGNORED_ENVIRONMENTS", "staging")
+
+	require.NoError(t, ConfigureE(Configuration{SDK_TOKEN: "new-sdk-token", API_KEY: "api-key"}))
+	assert.Equal(t, "new-sdk-token", Config.APIKey)
+	assert.Equal(t, defaultAsyncQueueSize, Config.AsyncQueueSize)
+	assert.Contains(t, logged.String(), "treblle: warning: TREBLLE_IGNORED_ENVIRONMENTS is not read by Configure; use TREBLLE_IGNORED_ENV")
+}
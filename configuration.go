@@ -1,6 +1,7 @@
 package treblle
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -11,22 +12,48 @@ var Config internalConfiguration
 
 // Configuration sets up and customizes communication with the Treblle API
 type Configuration struct {
-	SDK_TOKEN               string
-	API_KEY                 string
-	AdditionalFieldsToMask  []string
-	DefaultFieldsToMask     []string
-	MaskingEnabled          bool
-	Endpoint                string        // Custom endpoint for testing
-	BatchErrorEnabled       bool          // Enable batch error collection
-	BatchErrorSize          int           // Size of error batch before sending
-	BatchFlushInterval      time.Duration // Interval to flush errors if batch size not reached
-	SDKName                 string        // Defaults to "go"
-	SDKVersion              float64       // Defaults to 2.0
-	AsyncProcessingEnabled  bool          // Enable asynchronous request processing
-	MaxConcurrentProcessing int           // Maximum number of concurrent async operations (default: 10)
-	AsyncShutdownTimeout    time.Duration // Timeout for async shutdown (default: 5s)
-	IgnoredEnvironments     []string      // Environments where Treblle does not track requests
-	Debug                   bool          // Enable debug mode to see what's being sent to Treblle
+	SDK_TOKEN                string
+	API_KEY                  string
+	AdditionalFieldsToMask   []string
+	DefaultFieldsToMask      []string
+	MaskingEnabled           bool
+	Endpoint                 string                 // Custom endpoint for testing
+	BatchErrorEnabled        bool                   // Enable batch error collection
+	BatchErrorSize           int                    // Size of error batch before sending
+	BatchFlushInterval       time.Duration          // Interval to flush errors if batch size not reached
+	BatchErrorRateLimit      int                    // Maximum times the same error fingerprint is sent per BatchErrorRateWindow (0 = unlimited)
+	BatchErrorRateWindow     time.Duration          // Window for BatchErrorRateLimit (default: 1m)
+	BatchErrorMirrorRequests bool                   // Also add every request's errors to the batch error collector
+	SDKName                  string                 // Defaults to "go"
+	SDKVersion               float64                // Defaults to 2.0
+	AsyncProcessingEnabled   bool                   // Enable asynchronous request processing
+	MaxConcurrentProcessing  int                    // Maximum number of concurrent async operations (default: 10)
+	AsyncShutdownTimeout     time.Duration          // Timeout for async shutdown (default: 5s)
+	AsyncQueueSize           int                    // Maximum number of events waiting to be sent (default: 1000)
+	AsyncOverflowPolicy      OverflowPolicy         // What to drop when the queue is full (default: OverflowDropNewest)
+	AsyncEnqueueTimeout      time.Duration          // How long OverflowBlock waits for room in the queue (default: 100ms)
+	IgnoredEnvironments      []string               // Environments where Treblle does not track requests
+	Debug                    bool                   // Enable debug mode to see what's being sent to Treblle
+	GraphQLEnabled           bool                   // Group GraphQL traffic by operation instead of by HTTP path
+	GraphQLPaths             []string               // Paths that serve GraphQL (default: ["/graphql"])
+	JSONRPCEnabled           bool                   // Group JSON-RPC 2.0 traffic by method instead of by HTTP path
+	JSONRPCPaths             []string               // Paths that serve JSON-RPC (default: ["/rpc"])
+	RouteRules               []RouteRule            // Ordered segment rules for route normalization (default: DefaultRouteRules())
+	RoutePrefixRules         map[string][]RouteRule // Rules that replace RouteRules for paths under a prefix
+	RouteCardinalityLimit    int                    // Distinct values allowed after a route prefix before the segment is collapsed (0 disables)
+	RouteCardinalityPrefixes int                    // Maximum number of route prefixes tracked by the cardinality guard (default: 1000)
+	PanicResponse            PanicResponseFunc      // Writes the response when a handler panics (default: plain 500)
+	RepanicEnabled           bool                   // Re-panic after a recovered panic has been captured
+	StatusErrorsEnabled      bool                   // Add typed errors for failing response status codes
+	StatusErrorTypes         map[int]ErrorType      // Status code to error type mapping (default: DefaultStatusErrorTypes())
+	ErrorDetailsEnabled      bool                   // Attach stack traces, wrapped errors and error types to reported errors (recovered panics always carry a stack)
+	StackTraceDepth          int                    // Maximum number of stack frames recorded (default: 32)
+	StackTraceModulePath     string                 // Module path trimmed from stack frame functions and files, e.g. "github.com/acme/api"
+	Exporters                []Exporter             // Also receive every event, e.g. a FileExporter
+	DisableTreblle           bool                   // Deliver events only to Exporters
+	SampleRate               float64                // Fraction of requests captured, between 0 and 1 (default: 1)
+	IgnoredRoutes            []string               // Request paths that are never captured; a trailing "*" matches a prefix
+	ConfigFile               string                 // YAML, JSON or TOML file applied beneath environment variables and code (see LoadConfig)
 }
 
 // internalConfiguration is used for communication with Treblle API and contains optimizations
@@ -42,23 +69,68 @@ type internalConfiguration struct {
 	languageInfo            LanguageInfo
 	Debug                   bool
 	batchErrorCollector     *BatchErrorCollector
+	MirrorRequestErrors     bool
 	SDKName                 string
 	SDKVersion              float64
 	AsyncProcessingEnabled  bool
 	MaxConcurrentProcessing int
 	AsyncShutdownTimeout    time.Duration
+	AsyncQueueSize          int
+	AsyncOverflowPolicy     OverflowPolicy
+	AsyncEnqueueTimeout     time.Duration
 	IgnoredEnvironments     []string
+	GraphQLEnabled          bool
+	GraphQLPaths            []string
+	JSONRPCEnabled          bool
+	JSONRPCPaths            []string
+	RouteRules              []RouteRule
+	RoutePrefixRules        map[string][]RouteRule
+	routeCardinalityGuard   *routeCardinalityGuard
+	PanicResponse           PanicResponseFunc
+	RepanicEnabled          bool
+	StatusErrorsEnabled     bool
+	StatusErrorTypes        map[int]ErrorType
+	ErrorDetailsEnabled     bool
+	StackTraceDepth         int
+	StackTraceModulePath    string
+	Exporters               []Exporter
+	TreblleDisabled         bool
+	SampleRate              float64
+	IgnoredRoutes           []string
 }
 
+// Configure sets up the SDK. Each setting comes from, in order of precedence, the configuration
+// given in code, TREBLLE_* environment variables, the config file named by ConfigFile or
+// TREBLLE_CONFIG, and the defaults. Zero values in code count as unset, so code cannot turn off
+// a setting the file turns on, such as debug: true; remove it from the file instead.
+//
+// Invalid settings are defaulted or ignored; use ConfigureE or Configuration.Validate to report them.
 func Configure(config Configuration) {
-	if config.SDK_TOKEN != "" {
-		Config.APIKey = config.SDK_TOKEN
+	file, err := loadConfigFile(config)
+	if problems := validateConfiguration(config, file, err); problems != nil && (config.Debug || file.Debug) {
+		fmt.Printf("==== DEBUG: TREBLLE CONFIGURATION PROBLEMS ====\n")
+		for _, problem := range problems.Problems {
+			fmt.Printf("%s\n", problem)
+		}
+		fmt.Printf("================================\n")
+	}
+	configure(config, file)
+}
+
+// configure applies the configuration given in code on top of the environment and the config file
+func configure(config, file Configuration) {
+	// Fill the settings left unset in code from the config file
+	code := config
+	config = mergeConfiguration(config, file)
+
+	if token, source := resolveSDKToken(config, file); source != SourceDefault {
+		Config.APIKey = token
 	}
-	if config.API_KEY != "" {
-		Config.ProjectID = config.API_KEY
+	if apiKey, source := resolveAPIKey(config, file); source != SourceDefault {
+		Config.ProjectID = apiKey
 	}
-	if config.Endpoint != "" {
-		Config.Endpoint = config.Endpoint
+	if endpoint, source := resolveEndpoint(config, file); source != SourceDefault {
+		Config.Endpoint = endpoint
 	}
 
 	// Set debug mode
@@ -71,24 +143,12 @@ func Configure(config Configuration) {
 	// Initialize default masking settings
 	Config.MaskingEnabled = true
 
-	// Set SDK Name and Version (Can be overridden via ENV)
-	sdkName := "go"
-	if config.SDKName != "" {
-		sdkName = config.SDKName
-	}
-
-	sdkVersion := 2.0
-	if config.SDKVersion != 0 {
-		sdkVersion = config.SDKVersion
-	}
-
-	sdkVersionEnv, err := strconv.ParseFloat(os.Getenv("TREBLLE_SDK_VERSION"), 64)
-	if err == nil {
-		sdkVersion = sdkVersionEnv
-	}
+	// Set SDK Name and Version (from code, ENV or the config file)
+	Config.SDKName, _ = resolveSDKName(config, file)
+	Config.SDKVersion, _ = resolveSDKVersion(config, file)
 
-	Config.SDKName = getEnvOrDefault("TREBLLE_SDK_NAME", sdkName)
-	Config.SDKVersion = sdkVersion
+	// Start accepting events again after a Shutdown
+	resetShutdown()
 
 	// Configure async processing
 	Config.AsyncProcessingEnabled = config.AsyncProcessingEnabled
@@ -102,13 +162,29 @@ func Configure(config Configuration) {
 		Config.AsyncShutdownTimeout = 5 * time.Second
 	}
 
-	// Initialize batch error collector if enabled
+	Config.AsyncQueueSize = config.AsyncQueueSize
+	if Config.AsyncQueueSize <= 0 {
+		Config.AsyncQueueSize = defaultAsyncQueueSize
+	}
+	Config.AsyncOverflowPolicy = config.AsyncOverflowPolicy
+	if Config.AsyncOverflowPolicy == "" {
+		Config.AsyncOverflowPolicy = OverflowDropNewest
+	}
+	Config.AsyncEnqueueTimeout = config.AsyncEnqueueTimeout
+	if Config.AsyncEnqueueTimeout <= 0 {
+		Config.AsyncEnqueueTimeout = defaultAsyncEnqueueTimeout
+	}
+
+	// Initialize batch error collector if enabled, replacing the previous one
+	if Config.batchErrorCollector != nil {
+		Config.batchErrorCollector.Close()
+		Config.batchErrorCollector = nil
+	}
 	if config.BatchErrorEnabled {
-		if Config.batchErrorCollector != nil {
-			Config.batchErrorCollector.Close()
-		}
 		Config.batchErrorCollector = NewBatchErrorCollector(config.BatchErrorSize, config.BatchFlushInterval)
+		Config.batchErrorCollector.SetRateLimit(config.BatchErrorRateLimit, config.BatchErrorRateWindow)
 	}
+	Config.MirrorRequestErrors = config.BatchErrorMirrorRequests
 
 	// Load default fields to mask if not specified
 	if len(config.DefaultFieldsToMask) == 0 {
@@ -117,23 +193,80 @@ func Configure(config Configuration) {
 		Config.DefaultFieldsToMask = config.DefaultFieldsToMask
 	}
 
-	// Check for additional fields to mask from environment variables
-	envMaskedFields := getEnvMaskedFields()
-	if len(envMaskedFields) > 0 {
-		Config.AdditionalFieldsToMask = append(Config.AdditionalFieldsToMask, envMaskedFields...)
-	} else if len(config.AdditionalFieldsToMask) > 0 {
-		Config.AdditionalFieldsToMask = config.AdditionalFieldsToMask
-	}
+	// Additional fields to mask come from code, environment variables or the config file
+	additionalFields, additionalSource := resolveAdditionalFieldsToMask(config, file)
+	Config.AdditionalFieldsToMask = additionalFields
 
 	// Load ignored environments from config or environment variable
-	if len(config.IgnoredEnvironments) > 0 {
-		Config.IgnoredEnvironments = config.IgnoredEnvironments
-	} else {
-		defaultIgnoredEnvs := []string{"dev", "test", "testing"}
-		Config.IgnoredEnvironments = getEnvAsSlice("TREBLLE_IGNORED_ENV", defaultIgnoredEnvs)
+	Config.IgnoredEnvironments, _ = resolveIgnoredEnvironments(config, file)
+
+	// Configure GraphQL operation grouping
+	Config.GraphQLEnabled = config.GraphQLEnabled
+	Config.GraphQLPaths = config.GraphQLPaths
+	if len(Config.GraphQLPaths) == 0 {
+		Config.GraphQLPaths = []string{"/graphql"}
+	}
+
+	// Configure JSON-RPC method grouping
+	Config.JSONRPCEnabled = config.JSONRPCEnabled
+	Config.JSONRPCPaths = config.JSONRPCPaths
+	if len(Config.JSONRPCPaths) == 0 {
+		Config.JSONRPCPaths = []string{"/rpc"}
+	}
+
+	// Configure panic handling
+	Config.PanicResponse = config.PanicResponse
+	Config.RepanicEnabled = config.RepanicEnabled
+
+	// Configure status code error classification
+	Config.StatusErrorsEnabled = config.StatusErrorsEnabled
+	Config.StatusErrorTypes = config.StatusErrorTypes
+	if len(Config.StatusErrorTypes) == 0 {
+		Config.StatusErrorTypes = DefaultStatusErrorTypes()
+	}
+
+	// Configure error details
+	Config.ErrorDetailsEnabled = config.ErrorDetailsEnabled
+	Config.StackTraceDepth = config.StackTraceDepth
+	if Config.StackTraceDepth <= 0 {
+		Config.StackTraceDepth = defaultStackTraceDepth
+	}
+	Config.StackTraceModulePath = config.StackTraceModulePath
+
+	// Configure event exporters, closing the ones that are replaced
+	replaceExporters(config.Exporters)
+	Config.TreblleDisabled = config.DisableTreblle
+
+	// Configure sampling and ignored routes
+	Config.SampleRate = config.SampleRate
+	if Config.SampleRate <= 0 || Config.SampleRate > 1 {
+		Config.SampleRate = 1
+	}
+	Config.IgnoredRoutes = config.IgnoredRoutes
+
+	// Configure route normalization rules
+	Config.RouteRules = config.RouteRules
+	if len(Config.RouteRules) == 0 {
+		Config.RouteRules = DefaultRouteRules()
+	}
+	Config.RoutePrefixRules = config.RoutePrefixRules
+
+	// Guard against unbounded route cardinality when templates are not available
+	Config.routeCardinalityGuard = nil
+	if config.RouteCardinalityLimit > 0 {
+		Config.routeCardinalityGuard = newRouteCardinalityGuard(config.RouteCardinalityLimit, config.RouteCardinalityPrefixes)
 	}
 
 	Config.FieldsMap = generateFieldsToMask(Config.DefaultFieldsToMask, Config.AdditionalFieldsToMask)
+
+	// Publish the settings that can be changed at runtime
+	liveConfig.Store(newLiveSettings(code, Settings{
+		DefaultFieldsToMask:    Config.DefaultFieldsToMask,
+		AdditionalFieldsToMask: Config.AdditionalFieldsToMask,
+		IgnoredRoutes:          Config.IgnoredRoutes,
+		SampleRate:             Config.SampleRate,
+		Debug:                  Config.Debug,
+	}, additionalSource))
 }
 
 func getEnvMaskedFields() []string {
@@ -168,24 +301,18 @@ func getDefaultFieldsToMask() []string {
 }
 
 func generateFieldsToMask(defaultFields, additionalFields []string) map[string]bool {
-	fields := append(defaultFields, additionalFields...)
 	fieldsToMask := make(map[string]bool)
-	for _, field := range fields {
-		field = strings.TrimSpace(field)
-		if field != "" {
-			fieldsToMask[field] = true
+	for _, fields := range [][]string{defaultFields, additionalFields} {
+		for _, field := range fields {
+			field = strings.TrimSpace(field)
+			if field != "" {
+				fieldsToMask[field] = true
+			}
 		}
 	}
 	return fieldsToMask
 }
 
-func getEnvOrDefault(envKey, defaultValue string) string {
-	if value := os.Getenv(envKey); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
 func GetSDKInfo() map[string]string {
 	return map[string]string{
 		"SDK Name":    Config.SDKName,
@@ -201,17 +328,7 @@ func getEnvAsSlice(envKey string, defaultValues []string) []string {
 }
 
 func IsEnvironmentIgnored() bool {
-	currentEnv := os.Getenv("GO_ENV")
-	if currentEnv == "" {
-		currentEnv = os.Getenv("ENV")
-		if currentEnv == "" {
-			currentEnv = os.Getenv("ENVIRONMENT")
-			if currentEnv == "" {
-				currentEnv = os.Getenv("APP_ENV")
-			}
-		}
-	}
-
+	currentEnv, _ := currentEnvironment()
 	if currentEnv == "" {
 		return false
 	}
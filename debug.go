@@ -9,31 +9,9 @@ import (
 func DebugCommand() {
 	fmt.Println("=== Treblle Go SDK Debug Information ===")
 
-	// Initialize configuration from environment variables if not already set
+	// Load the configuration from the environment and config file if not already set
 	if Config.APIKey == "" {
-		// Read configuration from environment variables
-		apiKey := getEnvOrDefault("TREBLLE_SDK_TOKEN", "")
-		projectID := getEnvOrDefault("TREBLLE_API_KEY", "")
-		endpoint := getEnvOrDefault("TREBLLE_ENDPOINT", "")
-		ignoredEnvs := getEnvAsSlice("TREBLLE_IGNORED_ENVIRONMENTS", []string{"local", "development"})
-		
-		// Update Config with environment values
-		if apiKey != "" {
-			Config.APIKey = apiKey
-		}
-		if projectID != "" {
-			Config.ProjectID = projectID
-		}
-		if endpoint != "" {
-			Config.Endpoint = endpoint
-		}
-		if len(ignoredEnvs) > 0 {
-			Config.IgnoredEnvironments = ignoredEnvs
-		}
-		
-		// Set SDK info from constants
-		Config.SDKName = SDKName
-		Config.SDKVersion = SDKVersion
+		Configure(Configuration{})
 	}
 
 	// Display basic SDK configuration
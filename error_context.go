@@ -12,6 +12,7 @@ type ErrorContext struct {
 	Package    string `json:"package,omitempty"`
 	Component  string `json:"component,omitempty"`
 	StackTrace string `json:"stack_trace,omitempty"`
+	pcs        []uintptr
 }
 
 // getErrorContext returns contextual information about where an error occurred
@@ -44,6 +45,10 @@ func getErrorContext(skip int) ErrorContext {
 		buffer := make([]byte, 4096)
 		n := runtime.Stack(buffer, false)
 		context.StackTrace = string(buffer[:n])
+
+		// Keep the program counters for structured stack frames
+		pcs := make([]uintptr, stackTraceDepth())
+		context.pcs = pcs[:runtime.Callers(skip+2, pcs)]
 	}
 	
 	return context
@@ -59,3 +64,19 @@ type ErrorWithContext struct {
 func (e *ErrorWithContext) Error() string {
 	return fmt.Sprintf("%v [in %s.%s]", e.Err, e.Context.Package, e.Context.Function)
 }
+
+// Unwrap returns the wrapped error so errors.Is and errors.As see through the context
+func (e *ErrorWithContext) Unwrap() error {
+	return e.Err
+}
+
+// WrapError annotates err with the function, package and stack trace of the caller
+func WrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ErrorWithContext{
+		Err:     err,
+		Context: getErrorContext(1),
+	}
+}
@@ -23,11 +23,24 @@ const (
 
 // ErrorInfo represents detailed error information
 type ErrorInfo struct {
-	Message string    `json:"message"`
-	Type    ErrorType `json:"type"`
-	File    string    `json:"file"`
-	Line    int       `json:"line"`
-	Source  string    `json:"source"`
+	Message    string       `json:"message"`
+	Type       ErrorType    `json:"type"`
+	File       string       `json:"file"`
+	Line       int          `json:"line"`
+	Source     string       `json:"source"`
+	ErrorClass string       `json:"error_class,omitempty"` // Concrete Go type of the error (ErrorDetailsEnabled)
+	Chain      []ErrorCause `json:"chain,omitempty"`       // Wrapped errors (ErrorDetailsEnabled)
+	Stack      []StackFrame `json:"stack,omitempty"`       // Stack trace (panics, or ErrorDetailsEnabled)
+
+	// Fields set by CaptureError and CaptureMessage
+	Tags  map[string]string      `json:"tags,omitempty"`
+	Extra map[string]interface{} `json:"extra,omitempty"`
+
+	// Aggregation fields set by BatchErrorCollector
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Occurrences int    `json:"occurrences,omitempty"`
+	FirstSeen   string `json:"first_seen,omitempty"`
+	LastSeen    string `json:"last_seen,omitempty"`
 }
 
 // ErrorProvider manages error collection and processing
@@ -48,11 +61,17 @@ func (ep *ErrorProvider) AddError(err error, errType ErrorType, source string) {
 	if err == nil {
 		return
 	}
+	ep.addAt(err, err.Error(), errType, source, 2)
+}
 
-	ep.mu.Lock()
-	defer ep.mu.Unlock()
+// AddCustomError adds an error with custom message
+func (ep *ErrorProvider) AddCustomError(message string, errType ErrorType, source string) {
+	ep.addAt(nil, message, errType, source, 2)
+}
 
-	_, file, line, ok := runtime.Caller(1)
+// addAt adds an error attributed to the caller skip frames above addAt
+func (ep *ErrorProvider) addAt(err error, message string, errType ErrorType, source string, skip int) {
+	_, file, line, ok := runtime.Caller(skip)
 	if !ok {
 		file = "unknown"
 		line = 0
@@ -61,35 +80,27 @@ func (ep *ErrorProvider) AddError(err error, errType ErrorType, source string) {
 	// Clean file path (similar to Laravel's handling)
 	file = cleanFilePath(file)
 
-	ep.errors = append(ep.errors, ErrorInfo{
-		Message: err.Error(),
+	info := ErrorInfo{
+		Message: message,
 		Type:    errType,
 		File:    file,
 		Line:    line,
 		Source:  source,
-	})
-}
+	}
+	addErrorDetails(&info, err, skip)
 
-// AddCustomError adds an error with custom message
-func (ep *ErrorProvider) AddCustomError(message string, errType ErrorType, source string) {
 	ep.mu.Lock()
 	defer ep.mu.Unlock()
 
-	_, file, line, ok := runtime.Caller(1)
-	if !ok {
-		file = "unknown"
-		line = 0
-	}
+	ep.errors = append(ep.errors, info)
+}
 
-	file = cleanFilePath(file)
+// AddErrorInfo adds a fully populated error entry, e.g. one extracted from a response body
+func (ep *ErrorProvider) AddErrorInfo(info ErrorInfo) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
 
-	ep.errors = append(ep.errors, ErrorInfo{
-		Message: message,
-		Type:    errType,
-		File:    file,
-		Line:    line,
-		Source:  source,
-	})
+	ep.errors = append(ep.errors, info)
 }
 
 // GetErrors returns all collected errors
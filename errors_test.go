@@ -1,6 +1,7 @@
 package treblle
 
 import (
+	"sync"
 	"testing"
 	"github.com/stretchr/testify/assert"
 )
@@ -47,11 +48,25 @@ func TestErrorProviderConcurrency(t *testing.T) {
 
 	// Test concurrent error adding
 	t.Run("ConcurrentAdd", func(t *testing.T) {
-		go ep.AddCustomError("Error 1", ValidationError, "test1")
-		go ep.AddCustomError("Error 2", ServerError, "test2")
-		go ep.AddCustomError("Error 3", MarshalError, "test3")
+		var wg sync.WaitGroup
+		for _, e := range []struct {
+			message string
+			errType ErrorType
+			file    string
+		}{
+			{"Error 1", ValidationError, "test1"},
+			{"Error 2", ServerError, "test2"},
+			{"Error 3", MarshalError, "test3"},
+		} {
+			wg.Add(1)
+			go func(message string, errType ErrorType, file string) {
+				defer wg.Done()
+				ep.AddCustomError(message, errType, file)
+			}(e.message, e.errType, e.file)
+		}
 
-		// Give time for goroutines to complete
+		// Wait for the goroutines to complete
+		wg.Wait()
 		errors := ep.GetErrors()
 		assert.LessOrEqual(t, len(errors), 3, "Should have at most 3 errors")
 		ep.Clear()
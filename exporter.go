@@ -0,0 +1,162 @@
+package treblle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// Exporter receives every event delivered by the SDK, in addition to or instead of Treblle
+type Exporter interface {
+	Export(event MetaData) error
+}
+
+// ExporterFunc adapts a function to the Exporter interface
+type ExporterFunc func(event MetaData) error
+
+// Export calls f(event)
+func (f ExporterFunc) Export(event MetaData) error {
+	return f(event)
+}
+
+// FileExporter appends events to a file, one JSON object per line
+type FileExporter struct {
+	mu   sync.Mutex
+	path string // Opened on the first export when file is nil
+	file *os.File
+}
+
+// NewFileExporter opens path for appending, creating it if needed
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{path: path, file: file}, nil
+}
+
+// Export writes the event as a single line
+func (e *FileExporter) Export(event MetaData) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		if e.file, err = os.OpenFile(e.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+	}
+	_, err = e.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file. It is reopened if another event is exported, for example after
+// Configure is called again following a Shutdown.
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// eventTarget is where an event is delivered. It is taken from the configuration when the event
+// is built, so events in flight are not affected by later configuration changes.
+type eventTarget struct {
+	exporters []Exporter
+	endpoint  string // Empty means the default Treblle endpoints
+	disabled  bool   // Deliver to the exporters only
+}
+
+// currentEventTarget returns the delivery settings of the current configuration
+func currentEventTarget() eventTarget {
+	return eventTarget{
+		exporters: Config.Exporters,
+		endpoint:  Config.Endpoint,
+		disabled:  Config.TreblleDisabled,
+	}
+}
+
+// replaceExporters installs the configured exporters and closes the replaced ones that implement
+// io.Closer, so that reconfiguring does not leak open files. Exporters kept by the new
+// configuration are left open.
+func replaceExporters(exporters []Exporter) {
+	replaced := Config.Exporters
+	Config.Exporters = exporters
+
+	for _, exporter := range replaced {
+		closer, ok := exporter.(io.Closer)
+		if !ok || containsExporter(exporters, exporter) {
+			continue
+		}
+		if err := closer.Close(); err != nil && debugEnabled() {
+			fmt.Printf("==== DEBUG: TREBLLE EXPORTER CLOSE FAILED ====\n")
+			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("================================\n")
+		}
+	}
+}
+
+// containsExporter reports whether exporters holds exporter. Exporters of types that cannot be
+// compared, such as ExporterFunc, never match.
+func containsExporter(exporters []Exporter, exporter Exporter) bool {
+	if !reflect.TypeOf(exporter).Comparable() {
+		return false
+	}
+	for _, candidate := range exporters {
+		if candidate == exporter {
+			return true
+		}
+	}
+	return false
+}
+
+// fileExporterFor returns the configured file exporter for path, or a new one, so that reloading
+// the configuration file keeps writing through the file that is already open
+func fileExporterFor(path string) *FileExporter {
+	for _, exporter := range Config.Exporters {
+		if file, ok := exporter.(*FileExporter); ok && file.path == path {
+			return file
+		}
+	}
+	return &FileExporter{path: path}
+}
+
+// closeExporters closes the configured exporters that hold resources, such as files
+func closeExporters() error {
+	var closeErr error
+	for _, exporter := range Config.Exporters {
+		if closer, ok := exporter.(io.Closer); ok {
+			if err := closer.Close(); err != nil && closeErr == nil {
+				closeErr = err
+			}
+		}
+	}
+	return closeErr
+}
+
+// deliver hands an event to the exporters of target and, unless disabled, sends it to Treblle
+func deliver(ctx context.Context, treblleInfo MetaData, target eventTarget) error {
+	for _, exporter := range target.exporters {
+		if err := exporter.Export(treblleInfo); err != nil && debugEnabled() {
+			fmt.Printf("==== DEBUG: TREBLLE EXPORT FAILED ====\n")
+			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("================================\n")
+		}
+	}
+
+	if target.disabled {
+		return nil
+	}
+	return sendToTreblleWithContext(ctx, treblleInfo, target.endpoint)
+}
@@ -0,0 +1,126 @@
+package treblle
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportersReceiveEvents(t *testing.T) {
+	restoreConfig(t)
+
+	var treblleRequests atomic.Int64
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if json.NewDecoder(r.Body).Decode(&meta) == nil && strings.HasPrefix(meta.Data.Request.RoutePath, "/export/") {
+			treblleRequests.Add(1)
+		}
+	}))
+	defer treblleServer.Close()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	file, err := NewFileExporter(path)
+	require.NoError(t, err)
+
+	exported := make(chan MetaData, 10)
+	Configure(Configuration{
+		SDK_TOKEN:      "test-sdk-token",
+		Endpoint:       treblleServer.URL,
+		Exporters:      []Exporter{file, ExporterFunc(func(event MetaData) error { exported <- event; return nil })},
+		DisableTreblle: true,
+	})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/export/1", nil))
+
+	select {
+	case event := <-exported:
+		assert.Equal(t, "/export/{id}", event.Data.Request.RoutePath)
+		assert.Equal(t, http.StatusAccepted, event.Data.Response.Code)
+	case <-time.After(2 * time.Second):
+		t.Fatal("exporter did not receive the event")
+	}
+	syncSends.wait()
+	assert.Zero(t, treblleRequests.Load(), "DisableTreblle skips the Treblle endpoint")
+
+	require.NoError(t, file.Close())
+	contents, err := os.Open(path)
+	require.NoError(t, err)
+	defer contents.Close()
+	scanner := bufio.NewScanner(contents)
+	require.True(t, scanner.Scan())
+	var line MetaData
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+	assert.Equal(t, "/export/{id}", line.Data.Request.RoutePath)
+	assert.False(t, scanner.Scan(), "one event per line")
+}
+
+func TestShutdownClosesExporters(t *testing.T) {
+	restoreConfig(t)
+	defer resetShutdown()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	file, err := NewFileExporter(path)
+	require.NoError(t, err)
+
+	config := Configuration{SDK_TOKEN: "test-sdk-token", Exporters: []Exporter{file}, DisableTreblle: true}
+	Configure(config)
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/export/1", nil))
+
+	require.NoError(t, Shutdown(context.Background()))
+	assert.Nil(t, file.file, "the file is closed once the events are delivered")
+
+	// Capturing again reopens the file
+	Configure(config)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/export/2", nil))
+	syncSends.wait()
+	require.NoError(t, file.Close())
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(contents), "\n"))
+}
+
+func TestReconfigureClosesReplacedExporters(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	t.Cleanup(func() { closeExporters() })
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "treblle.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("exporters:\n  - type: file\n    path: "+filepath.Join(dir, "file.jsonl")+"\n"), 0o644))
+	t.Setenv("TREBLLE_CONFIG", configPath)
+
+	// Reloading the configuration file keeps the open file exporter
+	Configure(Configuration{DisableTreblle: true})
+	fromFile := Config.Exporters[0].(*FileExporter)
+	require.NoError(t, fromFile.Export(MetaData{}))
+	Configure(Configuration{DisableTreblle: true})
+	assert.Same(t, fromFile, Config.Exporters[0])
+	assert.NotNil(t, fromFile.file)
+
+	// Exporters replaced from code are closed, exporters that are kept stay open
+	fromCode, err := NewFileExporter(filepath.Join(dir, "code.jsonl"))
+	require.NoError(t, err)
+	noop := ExporterFunc(func(event MetaData) error { return nil })
+	Configure(Configuration{DisableTreblle: true, Exporters: []Exporter{noop, fromCode}})
+	assert.Nil(t, fromFile.file, "the replaced file exporter is closed")
+	Configure(Configuration{DisableTreblle: true, Exporters: []Exporter{fromCode, noop}})
+	assert.NotNil(t, fromCode.file, "a kept exporter stays open")
+	Configure(Configuration{DisableTreblle: true})
+	assert.Nil(t, fromCode.file, "the replaced file exporter is closed")
+}
@@ -0,0 +1,40 @@
+package treblle
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Patterns that make otherwise identical error messages differ between occurrences
+var (
+	fingerprintUUIDPattern   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	fingerprintHexPattern    = regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]{8,}\b`)
+	fingerprintNumberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	fingerprintQuotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+)
+
+// normalizeErrorMessage strips IDs, numbers and quoted values from an error message so
+// that occurrences of the same error share a fingerprint
+func normalizeErrorMessage(message string) string {
+	message = fingerprintUUIDPattern.ReplaceAllString(message, "{uuid}")
+	message = fingerprintQuotedPattern.ReplaceAllString(message, "{str}")
+	message = fingerprintHexPattern.ReplaceAllStringFunc(message, func(match string) string {
+		// Long numbers are handled below; words like "deadbeef" need at least one digit
+		if !strings.ContainsAny(match, "abcdefABCDEF") || !strings.ContainsAny(match, "0123456789") {
+			return match
+		}
+		return "{hex}"
+	})
+	message = fingerprintNumberPattern.ReplaceAllString(message, "{n}")
+	return strings.TrimSpace(message)
+}
+
+// fingerprintError identifies an error by its type, source, location and normalized message
+func fingerprintError(err ErrorInfo) string {
+	key := fmt.Sprintf("%s|%s|%s:%d|%s", err.Type, err.Source, err.File, err.Line, normalizeErrorMessage(err.Message))
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
@@ -3,13 +3,13 @@ module github.com/Treblle/treblle-go/v2
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/go-chi/chi v1.5.5
 	github.com/stretchr/testify v1.8.4
-	golang.org/x/sync v0.11.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
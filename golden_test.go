@@ -0,0 +1,114 @@
+package treblle
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// Fields that legitimately differ between two captures of the same request
+var (
+	goldenTimestampPattern = regexp.MustCompile(`"timestamp":"[^"]*"`)
+	goldenLoadTimePattern  = regexp.MustCompile(`"load_time":[0-9.e+-]+`)
+)
+
+// captureGoldenPayload sends one request through Middleware and returns the raw payload sent to
+// Treblle, with the timestamp and load time fixed
+func captureGoldenPayload(t *testing.T, async bool) []byte {
+	received := make(chan []byte, 1)
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "golden-sdk-token", r.Header.Get("x-api-key"))
+		received <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer treblleServer.Close()
+	defer waitForSends()
+
+	useConfig(t, internalConfiguration{
+		APIKey:                  "golden-sdk-token",
+		ProjectID:               "golden-api-key",
+		Endpoint:                treblleServer.URL,
+		SDKName:                 "go",
+		SDKVersion:              2.0,
+		MaskingEnabled:          true,
+		FieldsMap:               map[string]bool{"password": true},
+		AsyncProcessingEnabled:  async,
+		MaxConcurrentProcessing: 1,
+		RouteRules:              DefaultRouteRules(),
+		serverInfo: ServerInfo{
+			Ip:        "10.0.0.1",
+			Timezone:  "UTC+0",
+			Software:  "go1.21",
+			Signature: "Treblle Go SDK",
+			Protocol:  "HTTP/1.1",
+			Os:        OsInfo{Name: "linux", Release: "6.0", Architecture: "amd64"},
+		},
+		languageInfo: LanguageInfo{Name: "go", Version: "go1.21"},
+	})
+	if async {
+		useTestProcessor(t, NewAsyncProcessor(1))
+	}
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":42,"password":"secret"}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/golden/42?page=1", strings.NewReader(`{"name":"Ada","password":"secret"}`))
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2.0", 2, 0
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golden-test")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Changing the configuration after capture must not alter the event
+	Config.APIKey = "changed-sdk-token"
+	Config.serverInfo.Protocol = "HTTP/1.0"
+
+	var payload []byte
+	select {
+	case payload = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	payload = goldenTimestampPattern.ReplaceAll(payload, []byte(`"timestamp":"2024-01-01 00:00:00"`))
+	return goldenLoadTimePattern.ReplaceAll(payload, []byte(`"load_time":1`))
+}
+
+func TestSyncAndAsyncPayloadsMatchGolden(t *testing.T) {
+	golden := filepath.Join("testdata", "event.golden.json")
+
+	sync := captureGoldenPayload(t, false)
+	async := captureGoldenPayload(t, true)
+	assert.Equal(t, string(sync), string(async), "Sync and async payloads should be byte-identical")
+
+	if *updateGolden {
+		var indented bytes.Buffer
+		require.NoError(t, json.Indent(&indented, sync, "", "  "))
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(golden, append(indented.Bytes(), '\n'), 0o644))
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+
+	var compacted bytes.Buffer
+	require.NoError(t, json.Compact(&compacted, expected))
+	assert.Equal(t, compacted.String(), string(sync))
+}
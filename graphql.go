@@ -0,0 +1,225 @@
+package treblle
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// graphQLOperation is a single operation in a GraphQL request body
+type graphQLOperation struct {
+	OperationName string `json:"operationName"`
+	Query         string `json:"query"`
+}
+
+// graphQLError is a single entry of the errors[] array in a GraphQL response
+type graphQLError struct {
+	Message   string        `json:"message"`
+	Path      []interface{} `json:"path"`
+	Locations []struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"locations"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+var (
+	// graphQLOperationPattern matches the first operation definition in a document
+	graphQLOperationPattern = regexp.MustCompile(`(?:^|[\s}])(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`)
+	// graphQLCommentPattern matches comments so they don't confuse operation detection
+	graphQLCommentPattern = regexp.MustCompile(`#[^\n]*`)
+)
+
+// isGraphQLRequest checks if the request targets one of the configured GraphQL paths
+func isGraphQLRequest(r *http.Request) bool {
+	if !Config.GraphQLEnabled {
+		return false
+	}
+
+	return pathMatchesAny(r.URL.Path, Config.GraphQLPaths)
+}
+
+// getGraphQLRoutePath derives a route like /graphql/query/GetUser from the GraphQL request.
+// Batched requests become /graphql/batch/GetUser,ListPosts. The base path is returned unchanged
+// if the operation cannot be determined.
+func getGraphQLRoutePath(r *http.Request, basePath string, body []byte) string {
+	var operations []graphQLOperation
+
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		operations = append(operations, graphQLOperation{
+			OperationName: query.Get("operationName"),
+			Query:         query.Get("query"),
+		})
+	} else {
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) == 0 {
+			return basePath
+		}
+
+		if trimmed[0] == '[' {
+			if err := json.Unmarshal(trimmed, &operations); err != nil {
+				return basePath
+			}
+		} else {
+			var operation graphQLOperation
+			if err := json.Unmarshal(trimmed, &operation); err != nil {
+				return basePath
+			}
+			operations = append(operations, operation)
+		}
+	}
+
+	if len(operations) == 0 {
+		return basePath
+	}
+
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	if len(operations) == 1 {
+		opType, name := parseGraphQLOperation(operations[0])
+		if opType == "" {
+			return basePath
+		}
+		return basePath + "/" + opType + "/" + name
+	}
+
+	names := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		_, name := parseGraphQLOperation(operation)
+		if name == "" {
+			name = "anonymous"
+		}
+		names = append(names, name)
+	}
+	return basePath + "/batch/" + strings.Join(names, ",")
+}
+
+// parseGraphQLOperation returns the operation type and name of a GraphQL operation
+func parseGraphQLOperation(operation graphQLOperation) (string, string) {
+	document := strings.TrimSpace(graphQLCommentPattern.ReplaceAllString(operation.Query, ""))
+	if document == "" {
+		return "", ""
+	}
+
+	name := operation.OperationName
+
+	// When the operation name is known, look up its own definition
+	if name != "" {
+		for _, match := range graphQLOperationPattern.FindAllStringSubmatch(document, -1) {
+			if match[2] == name {
+				return match[1], name
+			}
+		}
+	}
+
+	// Shorthand syntax: { user { id } }
+	if strings.HasPrefix(document, "{") {
+		if name == "" {
+			name = "anonymous"
+		}
+		return "query", name
+	}
+
+	match := graphQLOperationPattern.FindStringSubmatch(document)
+	if match == nil {
+		return "", ""
+	}
+	if name == "" {
+		name = match[2]
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+	return match[1], name
+}
+
+// extractGraphQLErrors converts the errors[] array of a GraphQL response (or batch of
+// responses) into ErrorInfo entries, regardless of the HTTP status code
+func extractGraphQLErrors(body []byte) []ErrorInfo {
+	type graphQLResponse struct {
+		Errors []graphQLError `json:"errors"`
+	}
+
+	var responses []graphQLResponse
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &responses); err != nil {
+			return nil
+		}
+	} else {
+		var response graphQLResponse
+		if err := json.Unmarshal(trimmed, &response); err != nil {
+			return nil
+		}
+		responses = append(responses, response)
+	}
+
+	var errors []ErrorInfo
+	for _, response := range responses {
+		for _, gqlErr := range response.Errors {
+			info := ErrorInfo{
+				Message: gqlErr.Message,
+				Type:    graphQLErrorType(gqlErr.Extensions.Code),
+				Source:  "graphql",
+			}
+
+			// File and Line are Go source locations, so the response path and the
+			// location in the query document go into Extra
+			extra := make(map[string]interface{})
+			if path := graphQLErrorPath(gqlErr.Path); path != "" {
+				extra["path"] = path
+			}
+			if len(gqlErr.Locations) > 0 {
+				extra["line"] = gqlErr.Locations[0].Line
+				extra["column"] = gqlErr.Locations[0].Column
+			}
+			if len(extra) > 0 {
+				info.Extra = extra
+			}
+			errors = append(errors, info)
+		}
+	}
+	return errors
+}
+
+// graphQLErrorType maps common GraphQL error codes onto Treblle error types
+func graphQLErrorType(code string) ErrorType {
+	switch strings.ToUpper(code) {
+	case "UNAUTHENTICATED":
+		return AuthenticationError
+	case "FORBIDDEN", "UNAUTHORIZED":
+		return AuthorizationError
+	case "NOT_FOUND":
+		return NotFoundError
+	case "BAD_USER_INPUT", "GRAPHQL_VALIDATION_FAILED", "GRAPHQL_PARSE_FAILED", "BAD_REQUEST":
+		return ValidationError
+	case "RATE_LIMITED", "TOO_MANY_REQUESTS":
+		return RateLimitError
+	default:
+		return ServerError
+	}
+}
+
+// graphQLErrorPath joins a GraphQL error path like ["user", "posts", 0] into "user.posts.0"
+func graphQLErrorPath(path []interface{}) string {
+	parts := make([]string, 0, len(path))
+	for _, segment := range path {
+		switch v := segment.(type) {
+		case string:
+			parts = append(parts, v)
+		case float64:
+			parts = append(parts, strconv.Itoa(int(v)))
+		}
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,113 @@
+package treblle
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLRoutePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"operation-name", `{"operationName":"GetUser","query":"query GetUser($id: ID!) { user(id: $id) { id } }"}`, "/graphql/query/GetUser"},
+		{"name-from-query", `{"query":"mutation UpdateUser { updateUser { id } }"}`, "/graphql/mutation/UpdateUser"},
+		{"shorthand", `{"query":"{ me { id } }"}`, "/graphql/query/anonymous"},
+		{"fragment-first", `{"operationName":"ListPosts","query":"fragment P on Post { id } query ListPosts { posts { ...P } }"}`, "/graphql/query/ListPosts"},
+		{"batch", `[{"query":"query GetUser { user { id } }"},{"query":"query ListPosts { posts { id } }"}]`, "/graphql/batch/GetUser,ListPosts"},
+		{"named-second", `{"operationName":"Rename","query":"query GetUser { user { id } } mutation Rename { rename { id } }"}`, "/graphql/mutation/Rename"},
+		{"batch-unknown", `[{"query":""},{"query":"query Foo { foo }"}]`, "/graphql/batch/anonymous,Foo"},
+		{"not-graphql", `{"foo":"bar"}`, "/graphql"},
+		{"invalid-json", `{"query":`, "/graphql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			assert.Equal(t, tt.expected, getGraphQLRoutePath(r, "/graphql", []byte(tt.body)))
+		})
+	}
+
+	t.Run("get-request", func(t *testing.T) {
+		query := url.Values{"query": {"query GetUser { user { id } }"}}
+		r := httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+		assert.Equal(t, "/graphql/query/GetUser", getGraphQLRoutePath(r, "/graphql", nil))
+	})
+}
+
+func TestExtractGraphQLErrors(t *testing.T) {
+	body := `{"data":null,"errors":[
+		{"message":"not logged in","path":["user"],"locations":[{"line":2,"column":3}],"extensions":{"code":"UNAUTHENTICATED"}},
+		{"message":"boom","path":["user","posts",1]}
+	]}`
+
+	errors := extractGraphQLErrors([]byte(body))
+	require.Len(t, errors, 2)
+
+	assert.Equal(t, "not logged in", errors[0].Message)
+	assert.Equal(t, AuthenticationError, errors[0].Type)
+	assert.Empty(t, errors[0].File)
+	assert.Zero(t, errors[0].Line)
+	assert.Equal(t, map[string]interface{}{"path": "user", "line": 2, "column": 3}, errors[0].Extra)
+	assert.Equal(t, "graphql", errors[0].Source)
+
+	assert.Equal(t, ServerError, errors[1].Type)
+	assert.Equal(t, map[string]interface{}{"path": "user.posts.1"}, errors[1].Extra)
+
+	batch := `[{"data":{}},{"errors":[{"message":"bad input","extensions":{"code":"BAD_USER_INPUT"}}]}]`
+	errors = extractGraphQLErrors([]byte(batch))
+	require.Len(t, errors, 1)
+	assert.Equal(t, ValidationError, errors[0].Type)
+
+	assert.Empty(t, extractGraphQLErrors([]byte(`{"data":{"user":{"id":1}}}`)))
+}
+
+func TestGraphQLMiddleware(t *testing.T) {
+	received := make(chan MetaData, 1)
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil && strings.HasPrefix(meta.Data.Request.RoutePath, "/graphql") {
+			received <- meta
+		}
+	}))
+	defer treblleServer.Close()
+
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		SDK_TOKEN:           "test-sdk-token",
+		API_KEY:             "test-api-key",
+		Endpoint:            treblleServer.URL,
+		DefaultFieldsToMask: []string{"password"},
+		GraphQLEnabled:      true,
+	})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"data":null,"errors":[{"message":"user not found","extensions":{"code":"NOT_FOUND"}}]}`))
+	}))
+
+	body := `{"operationName":"Login","query":"mutation Login($password: String!) { login(password: $password) }","variables":{"password":"hunter2"}}`
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, "/graphql/mutation/Login", meta.Data.Request.RoutePath)
+		assert.NotContains(t, string(meta.Data.Request.Body), "hunter2")
+		require.Len(t, meta.Data.Response.Errors, 1)
+		assert.Equal(t, NotFoundError, meta.Data.Response.Errors[0].Type)
+		assert.Equal(t, "user not found", meta.Data.Response.Errors[0].Message)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for GraphQL event")
+	}
+}
@@ -0,0 +1,162 @@
+package treblle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// jsonRPCMessage is a JSON-RPC 2.0 request or response object
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	ID      json.RawMessage `json:"id"`
+	Error   *jsonRPCError   `json:"error"`
+}
+
+// jsonRPCError is the error member of a JSON-RPC 2.0 response
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// jsonRPCExchange is a single JSON-RPC call paired with its response
+type jsonRPCExchange struct {
+	Request  RequestInfo
+	Response ResponseInfo
+	// Errors are the errors of the call itself, also included in Response.Errors
+	Errors []ErrorInfo
+}
+
+// isJSONRPCRequest checks if the request targets one of the configured JSON-RPC paths
+func isJSONRPCRequest(r *http.Request) bool {
+	if !Config.JSONRPCEnabled {
+		return false
+	}
+	return pathMatchesAny(r.URL.Path, Config.JSONRPCPaths)
+}
+
+// splitJSONRPCExchange turns a JSON-RPC request/response pair into one exchange per call.
+// Each call gets a route derived from its method, its own masked request body, the response
+// with the matching id and any JSON-RPC error converted into an ErrorInfo, with the method in
+// Extra. Requests that are not valid JSON-RPC are returned unchanged.
+func splitJSONRPCExchange(requestInfo RequestInfo, responseInfo ResponseInfo, responseBody []byte) []jsonRPCExchange {
+	unchanged := []jsonRPCExchange{{Request: requestInfo, Response: responseInfo}}
+
+	// The request body has already been masked by getRequestInfo
+	calls, rawCalls, ok := decodeJSONRPCMessages(requestInfo.Body)
+	if !ok || len(calls) == 0 {
+		return unchanged
+	}
+
+	// Index responses by id so they can be paired with their calls
+	responses, rawResponses, _ := decodeJSONRPCMessages(responseBody)
+	responsesByID := make(map[string]int, len(responses))
+	for i, response := range responses {
+		if id := jsonRPCID(response.ID); id != "" {
+			responsesByID[id] = i
+		}
+	}
+
+	basePath := strings.TrimSuffix(requestInfo.RoutePath, "/")
+
+	exchanges := make([]jsonRPCExchange, 0, len(calls))
+	for i, call := range calls {
+		if call.Method == "" {
+			return unchanged
+		}
+
+		callRequest := requestInfo
+		callRequest.RoutePath = basePath + "/" + call.Method
+		callRequest.Body = rawCalls[i]
+
+		callResponse := responseInfo
+		callResponse.Body = json.RawMessage("{}")
+		callResponse.Size = 0
+		callResponse.Errors = append([]ErrorInfo{}, responseInfo.Errors...)
+
+		var callErrors []ErrorInfo
+
+		// A single call gets the whole response even if the server omitted the id
+		responseIndex, found := responsesByID[jsonRPCID(call.ID)]
+		if !found && len(calls) == 1 && len(responses) == 1 {
+			responseIndex, found = 0, true
+		}
+
+		if found {
+			response := responses[responseIndex]
+			callResponse.Size = len(rawResponses[responseIndex])
+			if maskedBody, err := getMaskedJSON(rawResponses[responseIndex]); err == nil {
+				callResponse.Body = maskedBody
+			}
+			if response.Error != nil {
+				callErrors = append(callErrors, ErrorInfo{
+					Message: fmt.Sprintf("%s (code %d)", response.Error.Message, response.Error.Code),
+					Type:    jsonRPCErrorType(response.Error.Code),
+					Source:  "jsonrpc",
+					Extra:   map[string]interface{}{"method": call.Method},
+				})
+				callResponse.Errors = append(callResponse.Errors, callErrors...)
+			}
+		}
+
+		exchanges = append(exchanges, jsonRPCExchange{Request: callRequest, Response: callResponse, Errors: callErrors})
+	}
+
+	return exchanges
+}
+
+// decodeJSONRPCMessages decodes a single JSON-RPC message or a batch array, returning the
+// parsed messages alongside their raw JSON
+func decodeJSONRPCMessages(body []byte) ([]jsonRPCMessage, []json.RawMessage, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, nil, false
+	}
+
+	var raw []json.RawMessage
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, nil, false
+		}
+	} else {
+		raw = []json.RawMessage{json.RawMessage(trimmed)}
+	}
+
+	messages := make([]jsonRPCMessage, len(raw))
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &messages[i]); err != nil {
+			return nil, nil, false
+		}
+		if messages[i].JSONRPC != "2.0" {
+			return nil, nil, false
+		}
+	}
+	return messages, raw, true
+}
+
+// jsonRPCID returns a comparable key for a JSON-RPC id, or "" for notifications
+func jsonRPCID(id json.RawMessage) string {
+	trimmed := string(bytes.TrimSpace(id))
+	if trimmed == "" || trimmed == "null" {
+		return ""
+	}
+	return trimmed
+}
+
+// jsonRPCErrorType maps JSON-RPC 2.0 error codes onto Treblle error types
+func jsonRPCErrorType(code int) ErrorType {
+	switch {
+	case code == -32700, code == -32600, code == -32602:
+		// Parse error, invalid request, invalid params
+		return ValidationError
+	case code == -32601:
+		// Method not found
+		return NotFoundError
+	default:
+		// Internal error, implementation-defined server errors and application errors
+		return ServerError
+	}
+}
@@ -0,0 +1,133 @@
+package treblle
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitJSONRPCExchange(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{DefaultFieldsToMask: []string{"password"}})
+
+	requestInfo := RequestInfo{
+		RoutePath: "/rpc",
+		Body: json.RawMessage(`[
+			{"jsonrpc":"2.0","method":"user.get","params":{"id":1},"id":1},
+			{"jsonrpc":"2.0","method":"user.login","params":{"password":"*********"},"id":"b"},
+			{"jsonrpc":"2.0","method":"audit.log","params":{}}
+		]`),
+	}
+	responseInfo := ResponseInfo{Code: http.StatusOK}
+	responseBody := []byte(`[
+		{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":"b"},
+		{"jsonrpc":"2.0","result":{"id":1,"password":"secret"},"id":1}
+	]`)
+
+	exchanges := splitJSONRPCExchange(requestInfo, responseInfo, responseBody)
+	require.Len(t, exchanges, 3)
+
+	assert.Equal(t, "/rpc/user.get", exchanges[0].Request.RoutePath)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":{"id":1,"password":"*********"},"id":1}`, string(exchanges[0].Response.Body))
+	assert.Empty(t, exchanges[0].Response.Errors)
+
+	assert.Equal(t, "/rpc/user.login", exchanges[1].Request.RoutePath)
+	require.Len(t, exchanges[1].Response.Errors, 1)
+	assert.Equal(t, ValidationError, exchanges[1].Response.Errors[0].Type)
+	assert.Equal(t, "Invalid params (code -32602)", exchanges[1].Response.Errors[0].Message)
+	assert.Equal(t, "jsonrpc", exchanges[1].Response.Errors[0].Source)
+	assert.Empty(t, exchanges[1].Response.Errors[0].File)
+	assert.Equal(t, map[string]interface{}{"method": "user.login"}, exchanges[1].Response.Errors[0].Extra)
+	assert.Equal(t, exchanges[1].Response.Errors, exchanges[1].Errors)
+
+	// Notifications have no response
+	assert.Equal(t, "/rpc/audit.log", exchanges[2].Request.RoutePath)
+	assert.Equal(t, json.RawMessage("{}"), exchanges[2].Response.Body)
+	assert.Equal(t, 0, exchanges[2].Response.Size)
+}
+
+func TestSplitJSONRPCExchangeNotJSONRPC(t *testing.T) {
+	requestInfo := RequestInfo{RoutePath: "/rpc", Body: json.RawMessage(`{"foo":"bar"}`)}
+	exchanges := splitJSONRPCExchange(requestInfo, ResponseInfo{}, nil)
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, "/rpc", exchanges[0].Request.RoutePath)
+}
+
+func TestJSONRPCErrorType(t *testing.T) {
+	assert.Equal(t, ValidationError, jsonRPCErrorType(-32700))
+	assert.Equal(t, ValidationError, jsonRPCErrorType(-32600))
+	assert.Equal(t, NotFoundError, jsonRPCErrorType(-32601))
+	assert.Equal(t, ServerError, jsonRPCErrorType(-32603))
+	assert.Equal(t, ServerError, jsonRPCErrorType(-32050))
+}
+
+func TestJSONRPCMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	routes := []string{}
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil && strings.HasPrefix(meta.Data.Request.RoutePath, "/rpc/") {
+			mu.Lock()
+			routes = append(routes, meta.Data.Request.RoutePath)
+			mu.Unlock()
+		}
+	}))
+	defer treblleServer.Close()
+
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		SDK_TOKEN:      "test-sdk-token",
+		API_KEY:        "test-api-key",
+		Endpoint:       treblleServer.URL,
+		JSONRPCEnabled: true,
+	})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"jsonrpc":"2.0","result":1,"id":1},{"jsonrpc":"2.0","result":2,"id":2}]`))
+	}))
+
+	body := `[{"jsonrpc":"2.0","method":"math.add","id":1},{"jsonrpc":"2.0","method":"math.sub","id":2}]`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(routes) == 2
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.ElementsMatch(t, []string{"/rpc/math.add", "/rpc/math.sub"}, routes)
+}
+
+func TestJSONRPCErrorsAreMirrored(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		JSONRPCEnabled:           true,
+		BatchErrorMirrorRequests: true,
+		DisableTreblle:           true,
+	})
+	collector := useTestCollector(t)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"jsonrpc":"2.0","result":1,"id":1},{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":2}]`))
+	}))
+
+	body := `[{"jsonrpc":"2.0","method":"math.add","id":1},{"jsonrpc":"2.0","method":"math.pow","id":2}]`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+
+	errs := collectedErrors(collector)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "Method not found (code -32601)", errs[0].Message)
+	assert.Equal(t, NotFoundError, errs[0].Type)
+	assert.Equal(t, "/rpc/math.pow", errs[0].Tags["route"])
+}
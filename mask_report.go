@@ -0,0 +1,87 @@
+package treblle
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MaskedField describes a value replaced by masking
+type MaskedField struct {
+	Path  string // Path of the masked value, e.g. "user.password", "items[0].token" or a header name
+	Field string // Configured field name that matched
+	Rule  string // Setting the field comes from: DefaultFieldsToMask, AdditionalFieldsToMask, TREBLLE_MASKED_FIELDS or ConfigFile
+}
+
+// maskReport collects the values masked by the masking functions; a nil report records nothing
+type maskReport struct {
+	fields []MaskedField
+}
+
+// add records that the value at path was masked because of key
+func (r *maskReport) add(path, key string) {
+	if r == nil {
+		return
+	}
+	field, _ := matchMaskedField(key)
+	r.fields = append(r.fields, MaskedField{Path: path, Field: field, Rule: maskingRule(field)})
+}
+
+// sorted returns the recorded fields ordered by path
+func (r *maskReport) sorted() []MaskedField {
+	sort.Slice(r.fields, func(i, j int) bool { return r.fields[i].Path < r.fields[j].Path })
+	return r.fields
+}
+
+// maskingRule returns the setting a masked field was configured by
+func maskingRule(field string) string {
+	settings := currentSettings()
+	if containsField(settings.DefaultFieldsToMask, field) {
+		return "DefaultFieldsToMask"
+	}
+	switch settings.additionalSource {
+	case SourceEnv:
+		return "TREBLLE_MASKED_FIELDS"
+	case SourceFile:
+		return "ConfigFile"
+	}
+	return "AdditionalFieldsToMask"
+}
+
+// containsField reports whether fields contains field, ignoring surrounding whitespace
+func containsField(fields []string, field string) bool {
+	for _, candidate := range fields {
+		if strings.TrimSpace(candidate) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// joinMaskPath appends an object key to a masking path
+func joinMaskPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// MaskJSON masks a JSON document exactly as request and response bodies are masked and reports
+// every masked path. It uses the masking configuration set by Configure.
+func MaskJSON(data []byte) (json.RawMessage, []MaskedField, error) {
+	report := &maskReport{}
+	masked, err := maskJSONReport(data, report)
+	if err != nil {
+		return nil, nil, err
+	}
+	return masked, report.sorted(), nil
+}
+
+// MaskHeaders masks headers exactly as request and response headers are masked and reports every
+// masked header. It uses the masking configuration set by Configure.
+func MaskHeaders(header http.Header) (map[string]interface{}, []MaskedField) {
+	report := &maskReport{}
+	masked := maskHeadersReport(header, report)
+	return masked, report.sorted()
+}
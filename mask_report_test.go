@@ -0,0 +1,85 @@
+package treblle
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskJSONReport(t *testing.T) {
+	restoreConfig(t)
+	t.Setenv("TREBLLE_MASKED_FIELDS", "pin,token")
+
+	Configure(Configuration{
+		DefaultFieldsToMask: []string{"password"},
+	})
+
+	masked, fields, err := MaskJSON([]byte(`{"id":1,"password":"secret","users":[{"pin":"1234","name":"a"}],"x-token":"abc"}`))
+	require.NoError(t, err)
+
+	// The masked output is identical to the runtime masking
+	runtime, err := getMaskedJSON([]byte(`{"id":1,"password":"secret","users":[{"pin":"1234","name":"a"}],"x-token":"abc"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(runtime), string(masked))
+
+	assert.Equal(t, []MaskedField{
+		{Path: "password", Field: "password", Rule: "DefaultFieldsToMask"},
+		{Path: "users[0].pin", Field: "pin", Rule: "TREBLLE_MASKED_FIELDS"},
+	}, fields, "x-token only matches fields named with an x- prefix")
+
+	_, _, err = MaskJSON([]byte(`{"id"`))
+	assert.Error(t, err)
+}
+
+func TestMaskHeadersReport(t *testing.T) {
+	restoreConfig(t)
+	t.Setenv("TREBLLE_MASKED_FIELDS", "")
+
+	Configure(Configuration{
+		DefaultFieldsToMask:    []string{"authorization"},
+		AdditionalFieldsToMask: []string{"x-session"},
+	})
+
+	masked, fields := MaskHeaders(http.Header{
+		"Authorization": {"Bearer abc"},
+		"Session":       {"one", "two"},
+		"Accept":        {"*/*"},
+	})
+
+	body, _ := json.Marshal(masked)
+	assert.JSONEq(t, `{"Authorization":"Bearer *********","Session":["*********","*********"],"Accept":"*/*"}`, string(body))
+	assert.Equal(t, []MaskedField{
+		{Path: "Authorization", Field: "authorization", Rule: "DefaultFieldsToMask"},
+		{Path: "Session", Field: "x-session", Rule: "AdditionalFieldsToMask"},
+	}, fields)
+}
+
+func TestMaskingRuleFollowsResolvedSource(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	rule := func() string {
+		_, fields, err := MaskJSON([]byte(`{"pin":"1234"}`))
+		require.NoError(t, err)
+		require.Len(t, fields, 1)
+		return fields[0].Rule
+	}
+
+	// Fields set in code take precedence over the environment
+	t.Setenv("TREBLLE_MASKED_FIELDS", "pin")
+	Configure(Configuration{AdditionalFieldsToMask: []string{"pin"}})
+	assert.Equal(t, "AdditionalFieldsToMask", rule())
+	Configure(Configuration{})
+	assert.Equal(t, "TREBLLE_MASKED_FIELDS", rule())
+
+	t.Setenv("TREBLLE_MASKED_FIELDS", "")
+	path := filepath.Join(t.TempDir(), "treblle.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("additional_fields_to_mask: [pin]\n"), 0o644))
+	Configure(Configuration{ConfigFile: path})
+	assert.Equal(t, "ConfigFile", rule())
+}
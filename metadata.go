@@ -19,6 +19,23 @@ type MetaData struct {
 	Data      DataInfo `json:"data"`
 }
 
+// newMetaData builds a complete event from the configuration at the time of the call. Events are
+// built when a request is captured so later configuration changes do not alter them.
+func newMetaData(serverInfo ServerInfo, requestInfo RequestInfo, responseInfo ResponseInfo) MetaData {
+	return MetaData{
+		ApiKey:    Config.APIKey,
+		ProjectID: Config.ProjectID,
+		Version:   Config.SDKVersion,
+		Sdk:       Config.SDKName,
+		Data: DataInfo{
+			Server:   serverInfo,
+			Language: Config.languageInfo,
+			Request:  requestInfo,
+			Response: responseInfo,
+		},
+	}
+}
+
 type DataInfo struct {
 	Server   ServerInfo   `json:"server"`
 	Language LanguageInfo `json:"language"`
@@ -17,21 +17,16 @@ func Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Skip ignored routes and requests left out by sampling
+		if !shouldCapture(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Create error provider for this request
 		errorProvider := NewErrorProvider()
 		defer errorProvider.Clear()
 
-		// Recover from panics
-		defer func() {
-			if err := recover(); err != nil {
-				errorProvider.AddCustomError(
-					fmt.Sprintf("panic recovered: %v", err),
-					UnhandledExceptionError,
-					"middleware",
-				)
-			}
-		}()
-
 		// Get the request tracker
 		tracker := GetRequestTracker()
 
@@ -39,14 +34,23 @@ func Middleware(next http.Handler) http.Handler {
 		startTime := time.Now()
 		r = tracker.StoreStartTime(r)
 
+		// Let handlers report their own errors through ReportError
+		r = withErrorProvider(r, errorProvider)
+
 		// Get request info before processing
 		requestInfo, errReqInfo := getRequestInfo(r, startTime, errorProvider)
 		if errReqInfo != nil && !errors.Is(errReqInfo, ErrNotJson) {
 			errorProvider.AddError(errReqInfo, ValidationError, "request_processing")
 		}
 
+		// Group GraphQL traffic by operation rather than by the single HTTP endpoint
+		graphQL := isGraphQLRequest(r)
+		if graphQL {
+			requestInfo.RoutePath = getGraphQLRoutePath(r, requestInfo.RoutePath, requestInfo.Body)
+		}
+
 		// Log the route path for debugging
-		if Config.Debug {
+		if debugEnabled() {
 			fmt.Printf("==== DEBUG: TREBLLE ROUTE PATH ====\n")
 			fmt.Printf("Original URL Path: %s\n", r.URL)
 			fmt.Printf("Normalized Route Path: %s\n", requestInfo.RoutePath)
@@ -68,7 +72,32 @@ func Middleware(next http.Handler) http.Handler {
 
 		// Intercept the response so it can be copied
 		rec := httptest.NewRecorder()
-		next.ServeHTTP(rec, r)
+		if recovered := serveAndRecover(next, rec, r); recovered != nil {
+			// Aborted handlers are not errors; let net/http handle them as usual
+			if recovered.value == http.ErrAbortHandler {
+				panic(recovered.value)
+			}
+
+			errorProvider.AddErrorInfo(recovered.errorInfo())
+
+			// Discard whatever the handler wrote before panicking and send a 500 instead
+			rec = httptest.NewRecorder()
+			panicResponse := Config.PanicResponse
+			if panicResponse == nil {
+				panicResponse = defaultPanicResponse
+			}
+			panicResponse(rec, r, recovered.value)
+
+			// Re-panic once the event has been handed off so outer recovery still sees it
+			if Config.RepanicEnabled {
+				defer func() {
+					if flusher, ok := w.(http.Flusher); ok {
+						flusher.Flush()
+					}
+					panic(recovered.value)
+				}()
+			}
+		}
 
 		// Copy everything from response recorder to response writer
 		for k, v := range rec.Header() {
@@ -91,37 +120,80 @@ func Middleware(next http.Handler) http.Handler {
 		// 3. The response is not JSON (we'll still track it)
 		responseInfo := getResponseInfo(rec, startTime, errorProvider)
 
-		// Add all collected errors to the response
-		responseInfo.Errors = errorProvider.GetErrors()
+		dispatchResponse(r, rec, graphQL, serverInfo, requestInfo, responseInfo, errorProvider)
+	})
+}
 
-		if Config.AsyncProcessingEnabled {
-			// Process asynchronously with controlled concurrency
-			GetAsyncProcessor().Process(requestInfo, responseInfo, errorProvider)
-		} else {
-			// Create metadata
-			ti := MetaData{
-				ApiKey:    Config.APIKey,
-				ProjectID: Config.ProjectID,
-				Version:   Config.SDKVersion,
-				Sdk:       Config.SDKName,
-				Data: DataInfo{
-					Server:   serverInfo,
-					Language: Config.languageInfo,
-					Request:  requestInfo,
-					Response: responseInfo,
-				},
-			}
+// dispatchResponse adds the errors carried by the recorded response, mirrors them and dispatches
+// the event. It is shared by Middleware and Capture so both group protocols the same way.
+func dispatchResponse(r *http.Request, rec *httptest.ResponseRecorder, graphQL bool, serverInfo ServerInfo, requestInfo RequestInfo, responseInfo ResponseInfo, errorProvider *ErrorProvider) {
+	// GraphQL reports failures in the body, usually with a 200 status
+	if graphQL {
+		for _, info := range extractGraphQLErrors(rec.Body.Bytes()) {
+			errorProvider.AddErrorInfo(info)
+		}
+	}
 
-			// Don't block execution while sending data to Treblle
-			go func(ti MetaData) {
-				defer func() {
-					if err := recover(); err != nil {
-						fmt.Printf("Panic recovered in goroutine: %v\n", err)
-						// Silently recover from panic
-					}
-				}()
-				sendToTreblle(ti)
-			}(ti)
+	// Classify failing responses that carry no error yet
+	addStatusError(errorProvider, rec.Code, rec.Header(), rec.Body.Bytes())
+
+	// Add all collected errors to the response
+	responseInfo.Errors = errorProvider.GetErrors()
+	mirrorRequestErrors(requestInfo, responseInfo.Errors)
+
+	// JSON-RPC batches are split into one event per call
+	if isJSONRPCRequest(r) {
+		for _, exchange := range splitJSONRPCExchange(requestInfo, responseInfo, rec.Body.Bytes()) {
+			// The errors of each call are mirrored with the route of the call
+			mirrorRequestErrors(exchange.Request, exchange.Errors)
+			dispatchEvent(serverInfo, exchange.Request, exchange.Response, errorProvider)
 		}
-	})
+		return
+	}
+
+	dispatchEvent(serverInfo, requestInfo, responseInfo, errorProvider)
+}
+
+// dispatchEvent ships a captured request/response pair to Treblle without blocking the caller.
+// The event is built here in both modes so sync and async payloads are identical.
+func dispatchEvent(serverInfo ServerInfo, requestInfo RequestInfo, responseInfo ResponseInfo, errorProvider *ErrorProvider) {
+	if isShutdown() {
+		debugShutdownDrop()
+		return
+	}
+
+	ti := newMetaData(serverInfo, requestInfo, responseInfo)
+	target := currentEventTarget()
+
+	if Config.AsyncProcessingEnabled {
+		// Process asynchronously with controlled concurrency
+		GetAsyncProcessor().enqueue(ti, target)
+		return
+	}
+
+	// Don't block execution while sending data to Treblle. The send is tracked before it starts,
+	// unless Shutdown began after the check above.
+	if !trackSyncSend() {
+		debugShutdownDrop()
+		return
+	}
+	go func(ti MetaData) {
+		defer syncSendDone()
+		defer func() {
+			if err := recover(); err != nil {
+				fmt.Printf("Panic recovered in goroutine: %v\n", err)
+				// Silently recover from panic
+			}
+		}()
+		sendToTreblle(ti, target)
+	}(ti)
+}
+
+// debugShutdownDrop logs an event dropped because the SDK is shut down
+func debugShutdownDrop() {
+	if debugEnabled() {
+		fmt.Printf("==== DEBUG: TREBLLE EVENT DROPPED ====\n")
+		fmt.Printf("Reason: SDK is shut down\n")
+		fmt.Printf("================================\n")
+	}
 }
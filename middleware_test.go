@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -177,7 +178,7 @@ func (s *TestSuite) TestMiddleware() {
 
 	for tn, tc := range testCases {
 		s.SetupTest()
-		treblleMuxCalled := false
+		var treblleMuxCalled atomic.Bool
 
 		mockURL := s.treblleMockServer.URL
 		log.Printf("Test case: %s, Mock URL: %s", tn, mockURL)
@@ -208,7 +209,7 @@ func (s *TestSuite) TestMiddleware() {
 				s.Require().Equal(string(expectedBody), string(treblleMetadata.Data.Response.Body))
 			}
 
-			treblleMuxCalled = true
+			treblleMuxCalled.Store(true)
 			w.WriteHeader(http.StatusOK)
 		})
 
@@ -247,8 +248,8 @@ func (s *TestSuite) TestMiddleware() {
 
 		// Wait for the async Treblle call to finish
 		time.Sleep(1 * time.Second)
-		log.Printf("After sleep - treblleMuxCalled: %v, expected: %v", treblleMuxCalled, tc.treblleCalled)
-		s.Require().Equal(tc.treblleCalled, treblleMuxCalled, tn)
+		log.Printf("After sleep - treblleMuxCalled: %v, expected: %v", treblleMuxCalled.Load(), tc.treblleCalled)
+		s.Require().Equal(tc.treblleCalled, treblleMuxCalled.Load(), tn)
 
 		s.TearDownTest()
 	}
@@ -0,0 +1,92 @@
+package treblle
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// PanicResponseFunc writes the response sent to the client when a handler panics
+type PanicResponseFunc func(w http.ResponseWriter, r *http.Request, recovered interface{})
+
+// recoveredPanic describes a panic recovered from a handler
+type recoveredPanic struct {
+	value interface{}
+	file  string
+	line  int
+	stack []StackFrame
+}
+
+// serveAndRecover calls the next handler and recovers any panic it raises
+func serveAndRecover(next http.Handler, w http.ResponseWriter, r *http.Request) (recovered *recoveredPanic) {
+	defer func() {
+		if value := recover(); value != nil {
+			recovered = newRecoveredPanic(value)
+		}
+	}()
+
+	next.ServeHTTP(w, r)
+	return nil
+}
+
+// newRecoveredPanic captures the panicking frame and a stack trace trimmed to the handler.
+// It must be called from the deferred function that recovered the panic.
+func newRecoveredPanic(value interface{}) *recoveredPanic {
+	recovered := &recoveredPanic{value: value, file: "unknown"}
+
+	pcs := make([]uintptr, 64+stackTraceDepth())
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	depth := stackTraceDepth()
+	panicking := false
+	for {
+		frame, more := frames.Next()
+
+		// Everything before runtime.gopanic belongs to the SDK's recovery
+		if !panicking {
+			panicking = frame.Function == "runtime.gopanic"
+		} else if strings.HasSuffix(frame.Function, ".serveAndRecover") {
+			break
+		} else if len(recovered.stack) == 0 && strings.HasPrefix(frame.Function, "runtime.") {
+			// Skip runtime helpers such as runtime.sigpanic for nil dereferences
+		} else if len(recovered.stack) < depth {
+			if len(recovered.stack) == 0 {
+				recovered.file = frame.File
+				recovered.line = frame.Line
+			}
+			recovered.stack = append(recovered.stack, newStackFrame(frame))
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return recovered
+}
+
+// errorInfo converts the recovered panic into an ErrorInfo entry. The stack is always attached,
+// as a panic is unusable without it; the error chain and type need ErrorDetailsEnabled.
+func (p *recoveredPanic) errorInfo() ErrorInfo {
+	info := ErrorInfo{
+		Message: fmt.Sprintf("panic recovered: %v", p.value),
+		Type:    UnhandledExceptionError,
+		File:    cleanFilePath(p.file),
+		Line:    p.line,
+		Source:  "middleware",
+		Stack:   p.stack,
+	}
+
+	if err, ok := p.value.(error); ok && Config.ErrorDetailsEnabled {
+		info.ErrorClass = errorClass(err)
+		info.Chain = errorChain(err)
+	}
+	return info
+}
+
+// defaultPanicResponse writes a plain 500 Internal Server Error
+func defaultPanicResponse(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
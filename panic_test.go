@@ -0,0 +1,123 @@
+package treblle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPanicTestServer(t *testing.T) (*httptest.Server, chan MetaData) {
+	received := make(chan MetaData, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil && meta.Data.Request.RoutePath == "/panic" {
+			received <- meta
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestMiddlewarePanicCapture(t *testing.T) {
+	server, received := newPanicTestServer(t)
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{SDK_TOKEN: "test-sdk-token", API_KEY: "test-api-key", Endpoint: server.URL})
+
+	var panicLine int
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		_, _, panicLine, _ = runtime.Caller(0)
+		panic("something broke")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "partial")
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, http.StatusInternalServerError, meta.Data.Response.Code)
+		require.Len(t, meta.Data.Response.Errors, 1)
+		errorInfo := meta.Data.Response.Errors[0]
+		assert.Equal(t, "panic recovered: something broke", errorInfo.Message)
+		assert.Equal(t, UnhandledExceptionError, errorInfo.Type)
+		assert.True(t, strings.HasSuffix(errorInfo.File, "panic_test.go"), errorInfo.File)
+		assert.Equal(t, panicLine+1, errorInfo.Line)
+		require.NotEmpty(t, errorInfo.Stack)
+		assert.Contains(t, errorInfo.Stack[0].Function, "TestMiddlewarePanicCapture")
+		assert.Equal(t, panicLine+1, errorInfo.Stack[0].Line)
+		for _, frame := range errorInfo.Stack {
+			assert.NotContains(t, frame.Function, "runtime.gopanic")
+			assert.NotContains(t, frame.Function, "serveAndRecover")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for panic event")
+	}
+}
+
+func TestMiddlewarePanicCustomResponseAndRepanic(t *testing.T) {
+	server, received := newPanicTestServer(t)
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		SDK_TOKEN: "test-sdk-token",
+		API_KEY:   "test-api-key",
+		Endpoint:  server.URL,
+		PanicResponse: func(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"try again"}`))
+		},
+		RepanicEnabled: true,
+	})
+
+	panicErr := errors.New("nil map")
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(panicErr)
+	}))
+
+	var outer interface{}
+	rec := httptest.NewRecorder()
+	func() {
+		defer func() { outer = recover() }()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+
+	assert.Equal(t, panicErr, outer)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"error":"try again"}`, rec.Body.String())
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, http.StatusServiceUnavailable, meta.Data.Response.Code)
+		require.Len(t, meta.Data.Response.Errors, 1)
+		assert.Equal(t, "panic recovered: nil map", meta.Data.Response.Errors[0].Message)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for panic event")
+	}
+}
+
+func TestMiddlewareAbortHandlerIsNotCaptured(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+}
@@ -0,0 +1,82 @@
+package treblle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+)
+
+// treblleErrorProviderKey is the key for storing the request's error provider
+const treblleErrorProviderKey contextKey = "treblle_error_provider"
+
+// withErrorProvider stores the error provider in the request context
+func withErrorProvider(r *http.Request, errorProvider *ErrorProvider) *http.Request {
+	ctx := context.WithValue(r.Context(), treblleErrorProviderKey, errorProvider)
+	return r.WithContext(ctx)
+}
+
+// FromContext returns the error provider of the request being captured by Middleware,
+// or nil if the context does not belong to a captured request
+func FromContext(ctx context.Context) *ErrorProvider {
+	if ctx == nil {
+		return nil
+	}
+	errorProvider, _ := ctx.Value(treblleErrorProviderKey).(*ErrorProvider)
+	return errorProvider
+}
+
+// ReportError attaches an error to the Treblle event of the current request. The file and line
+// are those of the ReportError call. If err wraps an *ErrorWithContext, its function and package
+// are used as the source. With ErrorDetailsEnabled the error chain, type and stack are attached
+// too. It is a no-op outside of a request captured by Middleware.
+//
+// Example:
+//
+//	if err := db.Save(user); err != nil {
+//		treblle.ReportError(r.Context(), err, treblle.ServerError)
+//		http.Error(w, "could not save user", http.StatusInternalServerError)
+//		return
+//	}
+func ReportError(ctx context.Context, err error, errType ErrorType) {
+	errorProvider := FromContext(ctx)
+	if errorProvider == nil || err == nil {
+		return
+	}
+
+	_, file, line, ok := runtime.Caller(1)
+	if !ok {
+		file = "unknown"
+		line = 0
+	}
+
+	info := ErrorInfo{
+		Message: err.Error(),
+		Type:    errType,
+		File:    cleanFilePath(file),
+		Line:    line,
+		Source:  "handler",
+	}
+
+	var withContext *ErrorWithContext
+	if errors.As(err, &withContext) {
+		if source := errorContextSource(withContext.Context); source != "" {
+			info.Source = source
+		}
+	}
+	addErrorDetails(&info, err, 1)
+
+	errorProvider.AddErrorInfo(info)
+}
+
+// errorContextSource describes where an ErrorWithContext originated, e.g. "example.com/app/users.Save"
+func errorContextSource(ctx ErrorContext) string {
+	switch {
+	case ctx.Package != "" && ctx.Function != "":
+		return ctx.Package + "." + ctx.Function
+	case ctx.Function != "":
+		return ctx.Function
+	default:
+		return ctx.Component
+	}
+}
@@ -0,0 +1,88 @@
+package treblle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportErrorOutsideRequest(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	// Must not panic without a captured request
+	ReportError(context.Background(), errors.New("ignored"), ServerError)
+}
+
+func TestReportError(t *testing.T) {
+	errorProvider := NewErrorProvider()
+	r := withErrorProvider(httptest.NewRequest(http.MethodGet, "/", nil), errorProvider)
+	require.Same(t, errorProvider, FromContext(r.Context()))
+
+	ReportError(r.Context(), errors.New("db timeout"), ServerError)
+	_, _, line, _ := runtime.Caller(0)
+
+	errs := errorProvider.GetErrors()
+	require.Len(t, errs, 1)
+	assert.Equal(t, "db timeout", errs[0].Message)
+	assert.Equal(t, ServerError, errs[0].Type)
+	assert.Equal(t, "handler", errs[0].Source)
+	assert.True(t, strings.HasSuffix(errs[0].File, "report_test.go"), errs[0].File)
+	assert.Equal(t, line-1, errs[0].Line)
+}
+
+func saveUser() error {
+	return WrapError(errors.New("duplicate email"))
+}
+
+func TestReportErrorWithContext(t *testing.T) {
+	errorProvider := NewErrorProvider()
+	ctx := withErrorProvider(httptest.NewRequest(http.MethodGet, "/", nil), errorProvider).Context()
+
+	err := fmt.Errorf("create user: %w", saveUser())
+	ReportError(ctx, err, ValidationError)
+
+	errs := errorProvider.GetErrors()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "duplicate email")
+	assert.Equal(t, "github.com/Treblle/treblle-go/v2.saveUser", errs[0].Source)
+}
+
+func TestReportErrorThroughMiddleware(t *testing.T) {
+	received := make(chan MetaData, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil && meta.Data.Request.RoutePath == "/report" {
+			received <- meta
+		}
+	}))
+	defer server.Close()
+
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{SDK_TOKEN: "test-sdk-token", API_KEY: "test-api-key", Endpoint: server.URL})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReportError(r.Context(), errors.New("payment declined"), ValidationError)
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/report", nil))
+
+	select {
+	case meta := <-received:
+		require.Len(t, meta.Data.Response.Errors, 1)
+		assert.Equal(t, "payment declined", meta.Data.Response.Errors[0].Message)
+		assert.Equal(t, ValidationError, meta.Data.Response.Errors[0].Type)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+}
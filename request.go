@@ -21,6 +21,7 @@ type RequestInfo struct {
 	Headers   json.RawMessage `json:"headers"`
 	Body      json.RawMessage `json:"body"`
 	Query     json.RawMessage `json:"query"`
+	Direction string          `json:"direction,omitempty"` // Set to "outbound" for requests captured by Transport
 }
 
 var ErrNotJson = errors.New("request body is not JSON")
@@ -147,6 +148,12 @@ func recoverBody(r *http.Request, bodyReaderCopy io.ReadCloser) {
 	r.Body = bodyReaderCopy
 }
 
+// NormalizeRoutePath returns the route path the SDK reports for a request path or router
+// template, using the configured route rules
+func NormalizeRoutePath(path string) string {
+	return normalizeRoutePath(path)
+}
+
 // normalizeRoutePath converts dynamic route segments to a consistent format
 // This helps Treblle to properly group requests under the same endpoint
 func normalizeRoutePath(path string) string {
@@ -161,48 +168,38 @@ func normalizeRoutePath(path string) string {
 		path = "/" + path
 	}
 
-	// Handle gorilla/mux style parameters with regex constraints
-	// Convert {id:[0-9]+} to {id}
-	if strings.Contains(path, "{") && strings.Contains(path, "}") {
-		segments := strings.Split(path, "/")
-		for i, segment := range segments {
-			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
-				// Extract just the parameter name before any constraints
-				paramName := segment[1 : len(segment)-1] // Remove { and }
-				if colonIdx := strings.Index(paramName, ":"); colonIdx != -1 {
-					paramName = paramName[:colonIdx] // Take everything before the colon
-					fmt.Printf("paramName: %s\n", paramName)
-				}
-				segments[i] = "{" + paramName + "}"
+	// Router templates are already grouped; only literal paths go through the route rules
+	segments := strings.Split(path, "/")
+	templated := false
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			// Handle gorilla/mux style parameters with regex constraints
+			// Convert {id:[0-9]+} to {id}
+			paramName := segment[1 : len(segment)-1] // Remove { and }
+			if colonIdx := strings.Index(paramName, ":"); colonIdx != -1 {
+				paramName = paramName[:colonIdx] // Take everything before the colon
 			}
+			segments[i] = "{" + paramName + "}"
+			templated = true
+		} else if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			// Convert :param format to {param} format
+			segments[i] = "{" + strings.TrimPrefix(segment, ":") + "}"
+			templated = true
 		}
-		path = strings.Join(segments, "/")
-	} else if strings.Contains(path, ":") {
-		// Convert :param format to {param} format
-		segments := strings.Split(path, "/")
-		for i, segment := range segments {
-			if strings.HasPrefix(segment, ":") {
-				paramName := strings.TrimPrefix(segment, ":")
-				segments[i] = "{" + paramName + "}"
-			}
+	}
+
+	if !templated {
+		rules := Config.RouteRules
+		if rules == nil {
+			rules = DefaultRouteRules()
 		}
-		path = strings.Join(segments, "/")
-	} else {
-		// Convert simple numeric segments and UUIDs to parameter placeholders
-		segments := strings.Split(path, "/")
-		for i, segment := range segments {
-			if segment == "" {
-				continue
-			}
-			// Check if segment is a numeric ID
-			if _, err := fmt.Sscanf(segment, "%d", new(int)); err == nil {
-				segments[i] = "{id}"
-			} else if len(segment) >= 20 && isUUID(segment) {
-				segments[i] = "{uuid}"
-			}
+		segments = applyRouteRules(segments, rules, Config.RoutePrefixRules)
+
+		if Config.routeCardinalityGuard != nil {
+			segments = Config.routeCardinalityGuard.apply(segments)
 		}
-		path = strings.Join(segments, "/")
 	}
+	path = strings.Join(segments, "/")
 
 	// Clean up any double slashes
 	for strings.Contains(path, "//") {
@@ -211,13 +208,3 @@ func normalizeRoutePath(path string) string {
 
 	return path
 }
-
-// isUUID checks if a string looks like a UUID
-func isUUID(s string) bool {
-	// More robust UUID check
-	if len(s) != 36 {
-		return false
-	}
-	parts := strings.Split(s, "-")
-	return len(parts) == 5 && len(parts[0]) == 8 && len(parts[1]) == 4 && len(parts[2]) == 4 && len(parts[3]) == 4 && len(parts[4]) == 12
-}
@@ -22,34 +22,8 @@ type ResponseInfo struct {
 // getResponseInfo extracts information from the response matching Laravel SDK structure
 func getResponseInfo(response *httptest.ResponseRecorder, startTime time.Time, errorProvider *ErrorProvider) ResponseInfo {
 	// Process headers (similar to Laravel's collect()->first())
-	headers := make(map[string]interface{})
-	for key, values := range response.Header() {
-		if len(values) == 0 {
-			continue
-		}
-		
-		// For multiple values, keep them as an array
-		if len(values) > 1 {
-			// If the field should be masked, mask each value
-			if shouldMaskField(key) {
-				maskedValues := make([]interface{}, len(values))
-				for i := range values {
-					maskedValues[i] = maskValue(values[i], key)
-				}
-				headers[key] = maskedValues
-			} else {
-				headers[key] = values
-			}
-		} else {
-			// Single value
-			if shouldMaskField(key) {
-				headers[key] = maskValue(values[0], key)
-			} else {
-				headers[key] = values[0]
-			}
-		}
-	}
-	
+	headers := maskHeaders(response.Header())
+
 	headerJSON, err := json.Marshal(headers)
 	if err != nil {
 		headerJSON = json.RawMessage("{}")
@@ -0,0 +1,135 @@
+package treblle
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RouteRule collapses dynamic path segments into a placeholder so that Treblle groups
+// requests like /users/42 and /users/43 under the same endpoint, /users/{id}.
+// A segment matches if Match returns true or, when Match is nil, if Pattern matches it.
+type RouteRule struct {
+	Placeholder string                    // Placeholder name without braces, e.g. "id"
+	Pattern     *regexp.Regexp            // Regular expression the whole segment must match
+	Match       func(segment string) bool // Predicate used instead of Pattern when set
+}
+
+// Built-in route rules
+var (
+	NumericIDRule = RegexRouteRule("id", `^\d+$`)
+	UUIDRule      = PredicateRouteRule("uuid", isUUID)
+	ULIDRule      = RegexRouteRule("ulid", `^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+	ObjectIDRule  = RegexRouteRule("objectid", `^[0-9a-fA-F]{24}$`)
+	HexHashRule   = RegexRouteRule("hash", `^(?:[0-9a-fA-F]{32}|[0-9a-fA-F]{40}|[0-9a-fA-F]{64}|[0-9a-fA-F]{128})$`)
+	DateRule      = RegexRouteRule("date", `^\d{4}-\d{2}-\d{2}$`)
+	EmailRule     = RegexRouteRule("email", `^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	SlugRule      = RegexRouteRule("slug", `^[a-z0-9]+(?:-[a-z0-9]+){2,}$`)
+)
+
+// RegexRouteRule creates a rule that replaces segments matching expr with {placeholder}.
+// It panics if expr is not a valid regular expression.
+func RegexRouteRule(placeholder, expr string) RouteRule {
+	return RouteRule{Placeholder: placeholder, Pattern: regexp.MustCompile(expr)}
+}
+
+// PredicateRouteRule creates a rule that replaces segments for which match returns true
+func PredicateRouteRule(placeholder string, match func(segment string) bool) RouteRule {
+	return RouteRule{Placeholder: placeholder, Match: match}
+}
+
+// DefaultRouteRules returns the rules used when Configuration.RouteRules is empty.
+// SlugRule is not included because it would also collapse static multi-word segments.
+func DefaultRouteRules() []RouteRule {
+	return []RouteRule{
+		UUIDRule,
+		ULIDRule,
+		ObjectIDRule,
+		HexHashRule,
+		DateRule,
+		EmailRule,
+		NumericIDRule,
+	}
+}
+
+// matches checks if the rule applies to a path segment
+func (rule RouteRule) matches(segment string) bool {
+	if rule.Match != nil {
+		return rule.Match(segment)
+	}
+	if rule.Pattern != nil {
+		return rule.Pattern.MatchString(segment)
+	}
+	return false
+}
+
+// placeholder returns the segment replacement for the rule
+func (rule RouteRule) placeholder() string {
+	return "{" + rule.Placeholder + "}"
+}
+
+// applyRouteRules replaces every segment matched by a rule with the rule's placeholder.
+// The first matching rule wins. Prefix overrides apply only to segments after the prefix.
+func applyRouteRules(segments []string, rules []RouteRule, prefixRules map[string][]RouteRule) []string {
+	start := 0
+	if prefix, override, ok := longestRoutePrefix(segments, prefixRules); ok {
+		start = prefix
+		rules = override
+	}
+
+	for i := start; i < len(segments); i++ {
+		if segments[i] == "" {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.matches(segments[i]) {
+				segments[i] = rule.placeholder()
+				break
+			}
+		}
+	}
+	return segments
+}
+
+// longestRoutePrefix finds the longest configured prefix matching whole leading segments
+// and returns the number of segments it covers along with its rules
+func longestRoutePrefix(segments []string, prefixRules map[string][]RouteRule) (int, []RouteRule, bool) {
+	if len(prefixRules) == 0 {
+		return 0, nil, false
+	}
+
+	prefixes := make([]string, 0, len(prefixRules))
+	for prefix := range prefixRules {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		prefixSegments := strings.Split("/"+strings.Trim(prefix, "/"), "/")
+		if len(prefixSegments) > len(segments) {
+			continue
+		}
+
+		matched := true
+		for i, segment := range prefixSegments {
+			if segments[i] != segment {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(prefixSegments), prefixRules[prefix], true
+		}
+	}
+	return 0, nil, false
+}
+
+// isUUID checks if a string looks like a UUID
+func isUUID(s string) bool {
+	// More robust UUID check
+	if len(s) != 36 {
+		return false
+	}
+	parts := strings.Split(s, "-")
+	return len(parts) == 5 && len(parts[0]) == 8 && len(parts[1]) == 4 && len(parts[2]) == 4 && len(parts[3]) == 4 && len(parts[4]) == 12
+}
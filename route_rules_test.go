@@ -0,0 +1,58 @@
+package treblle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRoutePathDefaultRules(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/123", "/users/{id}"},
+		{"/users/550e8400-e29b-41d4-a716-446655440000", "/users/{uuid}"},
+		{"/orders/01ARZ3NDEKTSV4RRFFQ69G5FAV", "/orders/{ulid}"},
+		{"/posts/507f1f77bcf86cd799439011", "/posts/{objectid}"},
+		{"/blobs/d41d8cd98f00b204e9800998ecf8427e", "/blobs/{hash}"},
+		{"/reports/2024-01-31", "/reports/{date}"},
+		{"/subscribers/jane.doe@example.com", "/subscribers/{email}"},
+		{"/posts/my-first-blog-post", "/posts/my-first-blog-post"},
+		{"/v1/users", "/v1/users"},
+		{"/users/{id:[0-9]+}", "/users/{id}"},
+		{"/users/:id/posts", "/users/{id}/posts"},
+		{"GET /api//users/42", "/api/users/{id}"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, normalizeRoutePath(tt.path), tt.path)
+	}
+}
+
+func TestNormalizeRoutePathCustomRules(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		RouteRules: append([]RouteRule{
+			SlugRule,
+			PredicateRouteRule("sku", func(segment string) bool {
+				return strings.HasPrefix(segment, "SKU")
+			}),
+		}, DefaultRouteRules()...),
+		RoutePrefixRules: map[string][]RouteRule{
+			"/files": {RegexRouteRule("name", `.+`)},
+		},
+	})
+
+	assert.Equal(t, "/posts/{slug}", normalizeRoutePath("/posts/my-first-blog-post"))
+	assert.Equal(t, "/products/{sku}/reviews/{id}", normalizeRoutePath("/products/SKU123/reviews/7"))
+	assert.Equal(t, "/files/{name}/{name}", normalizeRoutePath("/files/report.pdf/42"))
+	// Prefixes only match whole segments
+	assert.Equal(t, "/filesystem/{id}", normalizeRoutePath("/filesystem/42"))
+}
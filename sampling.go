@@ -0,0 +1,32 @@
+package treblle
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+)
+
+// shouldCapture reports whether the middleware captures a request, applying IgnoredRoutes and
+// SampleRate; a rate outside (0, 1) captures everything
+func shouldCapture(r *http.Request) bool {
+	settings := currentSettings()
+	if isRouteIgnored(r.URL.Path, settings.IgnoredRoutes) {
+		return false
+	}
+	rate := settings.SampleRate
+	return rate <= 0 || rate >= 1 || rand.Float64() < rate
+}
+
+// isRouteIgnored reports whether path matches one of the ignored routes
+func isRouteIgnored(path string, ignoredRoutes []string) bool {
+	for _, route := range ignoredRoutes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == route {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package treblle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoredRoutesAndSampling(t *testing.T) {
+	restoreConfig(t)
+
+	var captured int64
+	Configure(Configuration{
+		SDK_TOKEN:      "test-sdk-token",
+		IgnoredRoutes:  []string{"/health", "/internal/*"},
+		Exporters:      []Exporter{ExporterFunc(func(MetaData) error { atomic.AddInt64(&captured, 1); return nil })},
+		DisableTreblle: true,
+	})
+	t.Setenv("GO_ENV", "production")
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		syncSends.wait()
+		return rec.Code
+	}
+
+	for _, path := range []string{"/health", "/internal/metrics", "/internal/"} {
+		assert.Equal(t, http.StatusOK, serve(path), "ignored routes are still served")
+	}
+	assert.Zero(t, atomic.LoadInt64(&captured), "ignored routes are not captured")
+
+	serve("/healthz")
+	assert.EqualValues(t, 1, atomic.LoadInt64(&captured), "only exact routes and * prefixes match")
+
+	require.NoError(t, UpdateSettings(Settings{IgnoredRoutes: []string{"/health", "/internal/*"}, SampleRate: 0.000001}))
+	for i := 0; i < 20; i++ {
+		serve("/users")
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt64(&captured), "requests left out by sampling are not captured")
+}
@@ -0,0 +1,221 @@
+package treblle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Settings are the settings that can be changed while the SDK is running, with UpdateSettings,
+// ReloadConfig or WatchConfig. Every change swaps in a complete set, so a lookup never sees half
+// of an update.
+type Settings struct {
+	DefaultFieldsToMask    []string // Empty means the SDK defaults
+	AdditionalFieldsToMask []string
+	IgnoredRoutes          []string // Request paths that are never captured; a trailing "*" matches a prefix
+	SampleRate             float64  // Fraction of requests captured, between 0 and 1 (0 means 1)
+	Debug                  bool
+}
+
+// settingsSnapshot is an immutable set of Settings with the lookup tables derived from them
+type settingsSnapshot struct {
+	Settings
+	fieldsMap        map[string]bool
+	additionalSource ConfigSource // Where AdditionalFieldsToMask came from
+}
+
+// liveSettings holds the current settings of a configuration made by Configure
+type liveSettings struct {
+	current atomic.Pointer[settingsSnapshot]
+	code    Configuration // Configuration given in code, which keeps precedence when reloading
+	mu      sync.Mutex    // Serializes reloads so the newest file always wins
+	version string        // Version of the config file the settings were loaded from
+}
+
+// liveConfig holds the live settings of the last Configure. It is read by capturing and sending
+// goroutines, so it is swapped atomically rather than kept in Config.
+var liveConfig atomic.Pointer[liveSettings]
+
+// errNotConfigured is returned when settings are changed before Configure has been called
+var errNotConfigured = errors.New("treblle: Configure must be called before changing settings")
+
+// newLiveSettings returns the live settings for a configuration given in code
+func newLiveSettings(code Configuration, settings Settings, additionalSource ConfigSource) *liveSettings {
+	live := &liveSettings{code: code}
+	live.current.Store(newSettingsSnapshot(settings, additionalSource))
+	if path, _ := resolveConfigFile(code); path != "" {
+		live.version, _ = configFileVersion(path)
+	}
+	return live
+}
+
+// newSettingsSnapshot copies settings into a snapshot, so later changes by the caller have no effect
+func newSettingsSnapshot(settings Settings, additionalSource ConfigSource) *settingsSnapshot {
+	snapshot := &settingsSnapshot{Settings: Settings{
+		DefaultFieldsToMask:    append([]string(nil), settings.DefaultFieldsToMask...),
+		AdditionalFieldsToMask: append([]string(nil), settings.AdditionalFieldsToMask...),
+		IgnoredRoutes:          append([]string(nil), settings.IgnoredRoutes...),
+		SampleRate:             settings.SampleRate,
+		Debug:                  settings.Debug,
+	}, additionalSource: additionalSource}
+	if len(snapshot.DefaultFieldsToMask) == 0 {
+		snapshot.DefaultFieldsToMask = getDefaultFieldsToMask()
+	}
+	if snapshot.SampleRate <= 0 || snapshot.SampleRate > 1 {
+		snapshot.SampleRate = 1
+	}
+	snapshot.fieldsMap = generateFieldsToMask(snapshot.DefaultFieldsToMask, snapshot.AdditionalFieldsToMask)
+	return snapshot
+}
+
+// currentSettings returns the settings in effect. Without Configure, they are read from Config.
+func currentSettings() *settingsSnapshot {
+	if live := liveConfig.Load(); live != nil {
+		return live.current.Load()
+	}
+	return &settingsSnapshot{
+		Settings: Settings{
+			DefaultFieldsToMask:    Config.DefaultFieldsToMask,
+			AdditionalFieldsToMask: Config.AdditionalFieldsToMask,
+			IgnoredRoutes:          Config.IgnoredRoutes,
+			SampleRate:             Config.SampleRate,
+			Debug:                  Config.Debug,
+		},
+		fieldsMap: Config.FieldsMap,
+	}
+}
+
+// debugEnabled reports whether debug output is on
+func debugEnabled() bool {
+	return currentSettings().Debug
+}
+
+// CurrentSettings returns the settings in effect
+func CurrentSettings() Settings {
+	return newSettingsSnapshot(currentSettings().Settings, "").Settings
+}
+
+// UpdateSettings replaces the settings in effect, for example to mask another field during an
+// incident. It returns a *ConfigError and changes nothing if the settings are invalid. The
+// settings last until the next UpdateSettings, ReloadConfig or Configure; Config keeps the
+// values set by Configure.
+func UpdateSettings(settings Settings) error {
+	live := liveConfig.Load()
+	if live == nil {
+		return errNotConfigured
+	}
+	if problems := validateSettings(settings); problems != nil {
+		return &ConfigError{Problems: problems}
+	}
+	live.current.Store(newSettingsSnapshot(settings, SourceCode))
+	return nil
+}
+
+// ReloadConfig reads the config file and the environment again and applies their Settings.
+// Settings given to Configure in code keep precedence, as they did in Configure. If the file
+// cannot be loaded or the settings are invalid, the settings in effect are kept.
+func ReloadConfig() error {
+	live := liveConfig.Load()
+	if live == nil {
+		return errNotConfigured
+	}
+	return live.reload()
+}
+
+// WatchConfig polls the config file every interval and calls ReloadConfig when it changes, until
+// ctx is done. Failed reloads are logged and the settings in effect are kept. It returns an error
+// straight away if there is no config file, and should be started again after Configure.
+//
+// Example:
+//
+//	go treblle.WatchConfig(ctx, 5*time.Second)
+func WatchConfig(ctx context.Context, interval time.Duration) error {
+	live := liveConfig.Load()
+	if live == nil {
+		return errNotConfigured
+	}
+	path, _ := resolveConfigFile(live.code)
+	if path == "" {
+		return errors.New("treblle: no config file to watch; set ConfigFile or TREBLLE_CONFIG")
+	}
+
+	live.mu.Lock()
+	last := live.version
+	live.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// A missing file is usually being replaced; wait for the new one
+		version, err := configFileVersion(path)
+		if err != nil || version == last {
+			continue
+		}
+		last = version
+		if err := live.reload(); err != nil {
+			logWarning(fmt.Sprintf("config reload failed: %v", err))
+		}
+	}
+}
+
+// reload applies the settings from the config file and the environment
+func (l *liveSettings) reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Read the version first, so a change made while loading is picked up by the next check
+	path, _ := resolveConfigFile(l.code)
+	version, _ := configFileVersion(path)
+	file, err := loadConfigFile(l.code)
+	if err != nil {
+		return &ConfigError{Problems: []ConfigProblem{{Setting: "ConfigFile", Message: fmt.Sprintf("cannot be loaded: %v", err)}}}
+	}
+	settings, additionalSource := resolveSettings(l.code, file)
+	if problems := validateSettings(settings); problems != nil {
+		return &ConfigError{Problems: problems}
+	}
+	l.current.Store(newSettingsSnapshot(settings, additionalSource))
+	l.version = version
+
+	if settings.Debug {
+		fmt.Printf("==== DEBUG: TREBLLE SETTINGS RELOADED ====\n")
+		fmt.Printf("Masked fields: %v %v\n", settings.DefaultFieldsToMask, settings.AdditionalFieldsToMask)
+		fmt.Printf("Ignored routes: %v\n", settings.IgnoredRoutes)
+		fmt.Printf("Sample rate: %g\n", settings.SampleRate)
+		fmt.Printf("================================\n")
+	}
+	return nil
+}
+
+// resolveSettings returns the Settings for a configuration given in code and a config file, and
+// where AdditionalFieldsToMask came from
+func resolveSettings(config, file Configuration) (Settings, ConfigSource) {
+	merged := mergeConfiguration(config, file)
+	additionalFields, additionalSource := resolveAdditionalFieldsToMask(config, file)
+	return Settings{
+		DefaultFieldsToMask:    merged.DefaultFieldsToMask,
+		AdditionalFieldsToMask: additionalFields,
+		IgnoredRoutes:          merged.IgnoredRoutes,
+		SampleRate:             merged.SampleRate,
+		Debug:                  merged.Debug,
+	}, additionalSource
+}
+
+// configFileVersion identifies the contents of a file by its size and modification time
+func configFileVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()), nil
+}
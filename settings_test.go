@@ -0,0 +1,186 @@
+package treblle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSettings(t *testing.T) {
+	clearConfigEnv(t)
+
+	useConfig(t, internalConfiguration{})
+	assert.ErrorIs(t, UpdateSettings(Settings{}), errNotConfigured)
+
+	Configure(Configuration{SDK_TOKEN: "test-sdk-token", AdditionalFieldsToMask: []string{"pin"}})
+	assert.True(t, shouldMaskField("pin"))
+	assert.False(t, shouldMaskField("iban"))
+
+	fields := []string{"iban"}
+	require.NoError(t, UpdateSettings(Settings{AdditionalFieldsToMask: fields, IgnoredRoutes: []string{"/health"}, Debug: true}))
+	fields[0] = "changed"
+	assert.True(t, shouldMaskField("iban"), "settings are copied")
+	assert.False(t, shouldMaskField("pin"))
+	assert.True(t, shouldMaskField("password"), "empty DefaultFieldsToMask means the SDK defaults")
+	assert.True(t, debugEnabled())
+
+	current := CurrentSettings()
+	assert.Equal(t, []string{"iban"}, current.AdditionalFieldsToMask)
+	assert.Equal(t, []string{"/health"}, current.IgnoredRoutes)
+	assert.Equal(t, 1.0, current.SampleRate)
+	current.IgnoredRoutes[0] = "/changed"
+	assert.Equal(t, []string{"/health"}, CurrentSettings().IgnoredRoutes, "CurrentSettings returns a copy")
+
+	err := UpdateSettings(Settings{SampleRate: 2, IgnoredRoutes: []string{"metrics"}})
+	assert.EqualError(t, err, `treblle: invalid configuration: SampleRate must be between 0 and 1 (got 2); IgnoredRoutes entry "metrics" does not start with /`)
+	assert.True(t, shouldMaskField("iban"), "invalid settings are not applied")
+}
+
+func TestReloadConfig(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	path := filepath.Join(t.TempDir(), "treblle.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("additional_fields_to_mask: [pin]\nignored_routes: [/health]\n"), 0o644))
+	Configure(Configuration{SDK_TOKEN: "test-sdk-token", ConfigFile: path, SampleRate: 0.5})
+	assert.True(t, shouldMaskField("pin"))
+
+	require.NoError(t, os.WriteFile(path, []byte("additional_fields_to_mask: [otp]\nignored_routes: [/internal/*]\nsample_rate: 0.1\n"), 0o644))
+	require.NoError(t, ReloadConfig())
+	settings := CurrentSettings()
+	assert.Equal(t, []string{"otp"}, settings.AdditionalFieldsToMask)
+	assert.Equal(t, []string{"/internal/*"}, settings.IgnoredRoutes)
+	assert.Equal(t, 0.5, settings.SampleRate, "settings given in code keep precedence")
+	assert.False(t, shouldMaskField("pin"))
+
+	t.Setenv("TREBLLE_MASKED_FIELDS", "cvv")
+	require.NoError(t, ReloadConfig())
+	assert.Equal(t, []string{"cvv"}, CurrentSettings().AdditionalFieldsToMask, "the environment is read again")
+
+	require.NoError(t, os.WriteFile(path, []byte("ignored_routes: [health]\n"), 0o644))
+	assert.EqualError(t, ReloadConfig(), `treblle: invalid configuration: IgnoredRoutes entry "health" does not start with /`)
+	require.NoError(t, os.WriteFile(path, []byte("ignored_routs: []\n"), 0o644))
+	assert.ErrorContains(t, ReloadConfig(), "ConfigFile cannot be loaded")
+	assert.Equal(t, []string{"/internal/*"}, CurrentSettings().IgnoredRoutes, "failed reloads keep the settings in effect")
+}
+
+func TestWatchConfig(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	Configure(Configuration{SDK_TOKEN: "test-sdk-token"})
+	assert.ErrorContains(t, WatchConfig(context.Background(), time.Millisecond), "no config file to watch")
+
+	path := filepath.Join(t.TempDir(), "treblle.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"ignored_routes": ["/health"]}`), 0o644))
+	Configure(Configuration{SDK_TOKEN: "test-sdk-token", ConfigFile: path})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- WatchConfig(ctx, 5*time.Millisecond) }()
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"ignored_routes": ["/health", "/metrics"], "debug": false}`), 0o644))
+	assert.Eventually(t, func() bool {
+		return len(CurrentSettings().IgnoredRoutes) == 2
+	}, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestSettingsSwapWhileCapturing(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+
+	var mu sync.Mutex
+	var bodies []string
+	Configure(Configuration{
+		SDK_TOKEN:              "test-sdk-token",
+		AdditionalFieldsToMask: []string{"iban"},
+		DisableTreblle:         true,
+		Exporters: []Exporter{ExporterFunc(func(event MetaData) error {
+			mu.Lock()
+			defer mu.Unlock()
+			bodies = append(bodies, string(event.Data.Request.Body))
+			return nil
+		})},
+	})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			fields := []string{"pin", "iban"}[i%2:]
+			assert.NoError(t, UpdateSettings(Settings{AdditionalFieldsToMask: fields, IgnoredRoutes: []string{"/health"}, SampleRate: 1}))
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				req := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"iban":"DE89370400440532013000"}`))
+				req.Header.Set("Content-Type", "application/json")
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	syncSends.wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, bodies, 200)
+	for _, body := range bodies {
+		assert.NotContains(t, body, "DE89370400440532013000", "iban is masked by every swapped-in setting")
+	}
+}
+
+// restoreConfig restores the configuration and the live settings when the test ends, once the
+// events sent by the test have been delivered. A batch error collector started by the test is
+// closed, and one closed by the test is not restored.
+func restoreConfig(t *testing.T) {
+	originalConfig, originalLive := Config, liveConfig.Load()
+	t.Cleanup(func() {
+		waitForSends()
+		if collector := Config.batchErrorCollector; collector != nil && collector != originalConfig.batchErrorCollector {
+			collector.Close()
+		}
+		if collector := originalConfig.batchErrorCollector; collector != nil && collector.isClosed() {
+			originalConfig.batchErrorCollector = nil
+		}
+		Config = originalConfig
+		liveConfig.Store(originalLive)
+	})
+}
+
+// useConfig replaces the configuration for the test. The live settings are cleared, so settings
+// are read from config as they are before Configure is called.
+func useConfig(t *testing.T, config internalConfiguration) {
+	restoreConfig(t)
+	Config = config
+	liveConfig.Store(nil)
+}
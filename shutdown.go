@@ -1,12 +1,52 @@
 package treblle
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var (
+	// shutdown is set by Shutdown and cleared by Configure
+	shutdown atomic.Bool
+
+	// Events sent synchronously that have not completed yet
+	syncSends sendTracker
+)
+
+// drainGracePeriod is how long Shutdown waits for sends to stop after its deadline has passed
+// and they have been cancelled
+const drainGracePeriod = 100 * time.Millisecond
+
+// sendTracker counts sends in flight. New sends are refused once it is closed, so Shutdown can
+// stop accepting sends and wait for the tracked ones without missing any.
+type sendTracker struct {
+	mu      sync.Mutex
+	closed  bool
+	pending int64
+	idleCh  chan struct{} // Closed once pending drops to zero
+}
+
+// ShutdownError reports events that could not be delivered before the shutdown deadline
+type ShutdownError struct {
+	Lost int64 // Events dropped or abandoned during shutdown
+	Err  error // Why shutdown did not complete, usually context.DeadlineExceeded
+}
+
+// Error implements the error interface
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("treblle: shutdown incomplete, %d events lost: %v", e.Lost, e.Err)
+}
+
+// Unwrap returns the underlying context error
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
 // ShutdownOptions contains options for graceful shutdown
 type ShutdownOptions struct {
 	// Additional fields to be masked during shutdown
@@ -15,156 +55,241 @@ type ShutdownOptions struct {
 	ErrorProvider *ErrorProvider
 }
 
-// Shutdown sends data to Treblle before application shutdown
-// It's similar to the terminate method in the Laravel SDK
-func Shutdown(r *http.Request, w http.ResponseWriter, responseBody []byte, options *ShutdownOptions) {
-	// Create error provider if not provided
-	errorProvider := NewErrorProvider()
-	if options != nil && options.ErrorProvider != nil {
-		errorProvider = options.ErrorProvider
-	}
-	
-	var requestInfo RequestInfo
-	var startTime time.Time
-	
-	// Try to get request info from context if async processing is enabled
-	if Config.AsyncProcessingEnabled {
-		tracker := GetRequestTracker()
-		
-		if storedRequestInfo, ok := tracker.GetRequestInfo(r); ok {
-			requestInfo = storedRequestInfo
-		}
-		
-		if storedStartTime, ok := tracker.GetStartTime(r); ok {
-			startTime = storedStartTime
-		} else {
-			// Fallback to current time
-			startTime = time.Now().Add(-time.Millisecond)
-		}
-	} else {
-		// Get start time (using current time as we don't have the actual start time)
-		startTime = time.Now().Add(-time.Millisecond) // Subtract a millisecond to ensure duration is positive
-		
-		// Get request info
-		var errReqInfo error
-		requestInfo, errReqInfo = getRequestInfo(r, startTime, errorProvider)
-		if errReqInfo != nil && !errors.Is(errReqInfo, ErrNotJson) {
-			errorProvider.AddError(errReqInfo, ValidationError, "shutdown_request_processing")
-		}
+// ShutdownRequest sends data for a single request to Treblle. The status code is read from w
+// when it exposes a Status() int method, and the start time from the request tracker. It was
+// named Shutdown before Shutdown became the SDK-wide shutdown.
+//
+// Deprecated: Use Capture, which takes the status and timing explicitly.
+func ShutdownRequest(r *http.Request, w http.ResponseWriter, responseBody []byte, options *ShutdownOptions) {
+	resp := CapturedResponse{
+		Header: w.Header(),
+		Body:   responseBody,
 	}
-	
-	// Process headers for response info
-	headers := make(map[string]interface{})
-	for key, values := range w.Header() {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
+	if options != nil {
+		resp.ErrorProvider = options.ErrorProvider
 	}
-	
-	headersJson, err := json.Marshal(maskMap(headers))
-	if err != nil {
-		errorProvider.AddError(err, MarshalError, "header_encoding")
-	}
-	
-	// Create response info
-	responseInfo := ResponseInfo{
-		Headers:  json.RawMessage(headersJson),
-		Code:     http.StatusOK, // Default to 200 if not available
-		Size:     len(responseBody),
-		LoadTime: float64(time.Since(startTime).Milliseconds()),
-		Errors:   []ErrorInfo{},
-	}
-	
+
 	// If response writer is an http.ResponseWriter that allows status code retrieval
-	if rw, ok := w.(interface{ Status() int }); ok {
-		responseInfo.Code = rw.Status()
-	}
-	
-	// Process response body if available
-	if len(responseBody) > 0 {
-		// Try to mask if it's JSON
-		sanitizedBody, err := getMaskedJSON(responseBody)
-		if err == nil {
-			responseInfo.Body = sanitizedBody
-		} else {
-			// For non-JSON responses, just store the raw body as a JSON string
-			jsonBytes, err := json.Marshal(string(responseBody))
-			if err == nil {
-				responseInfo.Body = json.RawMessage(jsonBytes)
-			}
-		}
+	switch rw := w.(type) {
+	case interface{ Status() int }:
+		resp.StatusCode = rw.Status()
+	case *httptest.ResponseRecorder:
+		resp.StatusCode = rw.Code
 	}
-	
-	// Add all collected errors to the response
-	responseInfo.Errors = errorProvider.GetErrors()
-	
-	// Create metadata
-	ti := MetaData{
-		ApiKey:    Config.APIKey,
-		ProjectID: Config.ProjectID,
-		Version:   SDKVersion,
-		Sdk:       SDKName,
-		Data: DataInfo{
-			Server:   Config.serverInfo,
-			Language: Config.languageInfo,
-			Request:  requestInfo,
-			Response: responseInfo,
-		},
-	}
-	
-	// Flush any batch errors if batch error collector is enabled
-	if Config.batchErrorCollector != nil {
-		Config.batchErrorCollector.Close()
-	}
-	
-	// Send data to Treblle synchronously (not in a goroutine since we're shutting down)
-	sendToTreblle(ti)
-}
-
-// ShutdownWithCustomData sends custom request and response data to Treblle before shutdown
+
+	Capture(r.Context(), r, resp)
+}
+
+// ShutdownWithCustomData sends custom request and response data to Treblle
+//
+// Deprecated: Use Capture.
 func ShutdownWithCustomData(requestInfo RequestInfo, responseInfo ResponseInfo, errorProvider *ErrorProvider) {
 	// Add collected errors to the response if error provider is available
 	if errorProvider != nil {
 		responseInfo.Errors = errorProvider.GetErrors()
 	}
-	
-	// Create metadata
-	ti := MetaData{
-		ApiKey:    Config.APIKey,
-		ProjectID: Config.ProjectID,
-		Version:   SDKVersion,
-		Sdk:       SDKName,
-		Data: DataInfo{
-			Server:   Config.serverInfo,
-			Language: Config.languageInfo,
-			Request:  requestInfo,
-			Response: responseInfo,
-		},
-	}
-	
-	// Flush any batch errors if batch error collector is enabled
-	if Config.batchErrorCollector != nil {
-		Config.batchErrorCollector.Close()
-	}
-	
-	// Send data to Treblle synchronously
-	sendToTreblle(ti)
+
+	dispatchEvent(Config.serverInfo, requestInfo, responseInfo, errorProvider)
+}
+
+// Shutdown stops accepting events, sends the queued ones, flushes the batch error collector,
+// closes the exporters that implement io.Closer and closes connections to Treblle. Events that
+// cannot be sent before ctx is done are abandoned and reported in a *ShutdownError. Call
+// Configure to start capturing again.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := treblle.Shutdown(ctx); err != nil {
+//		log.Printf("treblle: %v", err)
+//	}
+func Shutdown(ctx context.Context) error {
+	// Sends already tracked are waited for below; later ones are dropped
+	syncSends.close()
+	shutdown.Store(true)
+
+	var lost int64
+	var shutdownErr error
+
+	// Drain the async queue
+	if processor := currentAsyncProcessor(); processor != nil {
+		n, err := processor.drain(ctx)
+		lost += n
+		shutdownErr = err
+	}
+
+	// Wait for events sent synchronously
+	select {
+	case <-syncSends.idle():
+	case <-ctx.Done():
+		lost += syncSends.count()
+		shutdownErr = ctx.Err()
+	}
+
+	// Flush batched errors
+	if collector := Config.batchErrorCollector; collector != nil {
+		if err := waitContext(ctx, collector.Close); err != nil {
+			shutdownErr = err
+		}
+	}
+
+	// Close exporters once nothing is left to deliver to them
+	if err := closeExporters(); err != nil && shutdownErr == nil {
+		shutdownErr = err
+	}
+
+	treblleClient.CloseIdleConnections()
+
+	if shutdownErr != nil {
+		return &ShutdownError{Lost: lost, Err: shutdownErr}
+	}
+	return nil
+}
+
+// RegisterOnShutdown shuts Treblle down, with the given timeout, when srv.Shutdown is called.
+// The returned channel receives the result of Shutdown. http.Server runs shutdown hooks without
+// waiting for in-flight requests, so events of requests still running at that point may be lost;
+// call Shutdown after srv.Shutdown returns if they must be delivered.
+//
+// Example:
+//
+//	done := treblle.RegisterOnShutdown(srv, 5*time.Second)
+//	srv.Shutdown(ctx)
+//	if err := <-done; err != nil {
+//		log.Printf("treblle: %v", err)
+//	}
+func RegisterOnShutdown(srv *http.Server, timeout time.Duration) <-chan error {
+	done := make(chan error, 1)
+	srv.RegisterOnShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		done <- Shutdown(ctx)
+	})
+	return done
 }
 
 // GracefulShutdown flushes any pending batch errors and ensures all data is sent to Treblle
-// This can be called during application shutdown to ensure all data is properly sent
+// This can be called during application shutdown to ensure all data is properly sent.
+// It waits up to AsyncShutdownTimeout; use Shutdown to pass a context and learn about lost events.
 func GracefulShutdown() {
-	// Wait for async processor to finish if enabled
-	if Config.AsyncProcessingEnabled {
-		timeout := 5 * time.Second
-		if Config.AsyncShutdownTimeout > 0 {
-			timeout = Config.AsyncShutdownTimeout
-		}
-		GetAsyncProcessor().Shutdown(timeout)
+	timeout := 5 * time.Second
+	if Config.AsyncShutdownTimeout > 0 {
+		timeout = Config.AsyncShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := Shutdown(ctx); err != nil && debugEnabled() {
+		fmt.Printf("==== DEBUG: TREBLLE SHUTDOWN ====\n")
+		fmt.Printf("Error: %v\n", err)
+		fmt.Printf("================================\n")
 	}
-	
-	// Flush batch errors if enabled
-	if Config.batchErrorCollector != nil {
-		Config.batchErrorCollector.Flush()
+}
+
+// isShutdown reports whether Shutdown has been called since the last Configure
+func isShutdown() bool {
+	return shutdown.Load()
+}
+
+// resetShutdown lets the SDK capture events again after a Shutdown
+func resetShutdown() {
+	syncSends.open()
+	shutdown.Store(false)
+	resetAsyncProcessor()
+}
+
+// trackSyncSend records an event being sent synchronously so Shutdown can wait for it. It
+// reports false once Shutdown has started, in which case the event must be dropped.
+func trackSyncSend() bool {
+	return syncSends.add()
+}
+
+// syncSendDone marks a synchronous send as complete
+func syncSendDone() {
+	syncSends.done()
+}
+
+// add tracks a new send unless the tracker is closed
+func (s *sendTracker) add() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.pending++
+	return true
+}
+
+// done marks a tracked send as complete
+func (s *sendTracker) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending--
+	if s.pending == 0 && s.idleCh != nil {
+		close(s.idleCh)
+		s.idleCh = nil
+	}
+}
+
+// close refuses new sends
+func (s *sendTracker) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// open accepts new sends again
+func (s *sendTracker) open() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = false
+}
+
+// count returns the number of sends in flight
+func (s *sendTracker) count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// idle returns a channel that is closed once no send is in flight
+func (s *sendTracker) idle() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	if s.idleCh == nil {
+		s.idleCh = make(chan struct{})
+	}
+	return s.idleCh
+}
+
+// wait blocks until no send is in flight
+func (s *sendTracker) wait() {
+	<-s.idle()
+}
+
+// waitContext runs wait and returns once it completes or ctx is done. wait must return
+// eventually, as it keeps running in the background after ctx is done.
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wait()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
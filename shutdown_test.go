@@ -1,11 +1,16 @@
 package treblle
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestShutdown(t *testing.T) {
@@ -33,8 +38,8 @@ func TestShutdown(t *testing.T) {
 		t.Fatalf("Failed to write response: %v", err)
 	}
 
-	// Test the Shutdown function
-	Shutdown(req, w, responseBody, nil)
+	// Test the ShutdownRequest function
+	ShutdownRequest(req, w, responseBody, nil)
 
 	// Test with custom options
 	errorProvider := NewErrorProvider()
@@ -45,7 +50,7 @@ func TestShutdown(t *testing.T) {
 		ErrorProvider:          errorProvider,
 	}
 	
-	Shutdown(req, w, responseBody, options)
+	ShutdownRequest(req, w, responseBody, options)
 
 	// Test ShutdownWithCustomData
 	// Create headers for request
@@ -124,3 +129,140 @@ func TestGracefulShutdown(t *testing.T) {
 		t.Fatal("Expected batch error collector to still exist after shutdown")
 	}
 }
+
+func TestShutdownDrainsQueuedEvents(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusOK)
+	processor := newQueueTestProcessor(t, collector, OverflowDropNewest)
+	useTestProcessor(t, processor)
+
+	processor.Process(RequestInfo{Url: "/queue/1"}, ResponseInfo{}, nil)
+	close(collector.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, Shutdown(ctx))
+
+	assert.Equal(t, int64(2), processor.Stats().Sent)
+
+	// No events are accepted once shut down
+	processor.Process(RequestInfo{Url: "/queue/2"}, ResponseInfo{}, nil)
+	assert.Equal(t, int64(1), processor.Stats().Dropped)
+	assert.True(t, isShutdown())
+}
+
+func TestShutdownReportsLostEvents(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusOK)
+	processor := newQueueTestProcessor(t, collector, OverflowDropNewest)
+	useTestProcessor(t, processor)
+	defer close(collector.release)
+
+	processor.Process(RequestInfo{Url: "/queue/1"}, ResponseInfo{}, nil)
+	processor.Process(RequestInfo{Url: "/queue/2"}, ResponseInfo{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := Shutdown(ctx)
+
+	var shutdownErr *ShutdownError
+	require.ErrorAs(t, err, &shutdownErr)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(3), shutdownErr.Lost, "the in-flight event and both queued events are lost")
+	assert.Equal(t, int64(0), processor.Stats().Sent)
+}
+
+func TestConfigureAfterShutdown(t *testing.T) {
+	collector := newBlockingCollector(t, http.StatusOK)
+	processor := newQueueTestProcessor(t, collector, OverflowDropNewest)
+	useTestProcessor(t, processor)
+	close(collector.release)
+
+	require.NoError(t, Shutdown(context.Background()))
+	assert.Same(t, processor, GetAsyncProcessor())
+
+	resetShutdown()
+	assert.False(t, isShutdown())
+	assert.NotSame(t, processor, GetAsyncProcessor(), "a new processor should be started after Configure")
+}
+
+func TestRegisterOnShutdown(t *testing.T) {
+	useTestProcessor(t, NewAsyncProcessor(1))
+
+	srv := &http.Server{Addr: "127.0.0.1:0"}
+	done := RegisterOnShutdown(srv, time.Second)
+	require.NoError(t, srv.Shutdown(context.Background()))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown was not called")
+	}
+	assert.True(t, isShutdown())
+}
+
+func TestShutdownWhileDispatching(t *testing.T) {
+	restoreConfig(t)
+	Config.AsyncProcessingEnabled = false
+	Config.Exporters = nil
+	Config.TreblleDisabled = true
+	t.Cleanup(resetShutdown)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					dispatchEvent(Config.serverInfo, RequestInfo{}, ResponseInfo{}, nil)
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, Shutdown(ctx))
+	assert.Zero(t, syncSends.count(), "sends are not tracked once Shutdown has waited for them")
+
+	close(stop)
+	wg.Wait()
+}
+
+func TestShutdownAbandonsSendsIgnoringCancellation(t *testing.T) {
+	restoreConfig(t)
+	exporting := make(chan struct{})
+	release := make(chan struct{})
+	Config.Exporters = []Exporter{ExporterFunc(func(event MetaData) error {
+		close(exporting)
+		<-release
+		return nil
+	})}
+	Config.TreblleDisabled = true
+
+	processor := NewAsyncProcessor(1)
+	useTestProcessor(t, processor)
+	t.Cleanup(func() { close(release) })
+
+	processor.Process(RequestInfo{Url: "/stuck"}, ResponseInfo{}, nil)
+	select {
+	case <-exporting:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the exporter was not called")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := Shutdown(ctx)
+	assert.Less(t, time.Since(start), time.Second, "Shutdown should not wait for sends that ignore the cancellation")
+
+	var shutdownErr *ShutdownError
+	require.ErrorAs(t, err, &shutdownErr)
+	assert.Equal(t, int64(1), shutdownErr.Lost)
+}
@@ -0,0 +1,137 @@
+package treblle
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+const (
+	// defaultStackTraceDepth is the number of frames recorded when StackTraceDepth is not set
+	defaultStackTraceDepth = 32
+	// maxErrorChainLength limits how many wrapped errors are recorded
+	maxErrorChainLength = 16
+)
+
+// StackFrame is a single frame of a structured stack trace
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// ErrorCause is an error found by unwrapping a reported error
+type ErrorCause struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// stackTraceDepth returns the configured maximum number of stack frames
+func stackTraceDepth() int {
+	if Config.StackTraceDepth > 0 {
+		return Config.StackTraceDepth
+	}
+	return defaultStackTraceDepth
+}
+
+// captureStack returns the stack of the caller skip frames above captureStack
+func captureStack(skip int) []StackFrame {
+	pcs := make([]uintptr, stackTraceDepth())
+	n := runtime.Callers(skip+2, pcs)
+	return stackFromPCs(pcs[:n])
+}
+
+// stackFromPCs converts program counters into trimmed stack frames
+func stackFromPCs(pcs []uintptr) []StackFrame {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	depth := stackTraceDepth()
+	stack := make([]StackFrame, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for len(stack) < depth {
+		frame, more := frames.Next()
+		stack = append(stack, newStackFrame(frame))
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// newStackFrame converts a runtime frame, trimming the configured module path
+func newStackFrame(frame runtime.Frame) StackFrame {
+	function := frame.Function
+	file := frame.File
+
+	if modulePath := strings.TrimSuffix(Config.StackTraceModulePath, "/"); modulePath != "" {
+		if strings.HasPrefix(function, modulePath+"/") || strings.HasPrefix(function, modulePath+".") {
+			function = function[len(modulePath)+1:]
+		}
+		if idx := strings.Index(file, modulePath+"/"); idx >= 0 {
+			file = file[idx+len(modulePath)+1:]
+		} else {
+			file = cleanFilePath(file)
+		}
+	} else {
+		file = cleanFilePath(file)
+	}
+
+	return StackFrame{Function: function, File: file, Line: frame.Line}
+}
+
+// errorChain returns every error wrapped by err, depth first, following both
+// errors.Unwrap and the Unwrap() []error form used by errors.Join
+func errorChain(err error) []ErrorCause {
+	var chain []ErrorCause
+
+	var walk func(error)
+	walk = func(current error) {
+		var children []error
+		switch wrapped := current.(type) {
+		case interface{ Unwrap() []error }:
+			children = wrapped.Unwrap()
+		default:
+			if child := errors.Unwrap(current); child != nil {
+				children = []error{child}
+			}
+		}
+
+		for _, child := range children {
+			if child == nil || len(chain) >= maxErrorChainLength {
+				continue
+			}
+			chain = append(chain, ErrorCause{Type: errorClass(child), Message: child.Error()})
+			walk(child)
+		}
+	}
+	walk(err)
+
+	return chain
+}
+
+// errorClass returns the concrete type name of an error, e.g. "*fs.PathError"
+func errorClass(err error) string {
+	return fmt.Sprintf("%T", err)
+}
+
+// addErrorDetails attaches the error chain, concrete type and caller stack to info when
+// ErrorDetailsEnabled is set. skip is the number of frames above addErrorDetails to start at.
+func addErrorDetails(info *ErrorInfo, err error, skip int) {
+	if !Config.ErrorDetailsEnabled || err == nil {
+		return
+	}
+
+	info.ErrorClass = errorClass(err)
+	info.Chain = errorChain(err)
+
+	// Prefer the stack captured where the error was wrapped
+	var withContext *ErrorWithContext
+	if errors.As(err, &withContext) && len(withContext.Context.pcs) > 0 {
+		info.Stack = stackFromPCs(withContext.Context.pcs)
+		return
+	}
+	info.Stack = captureStack(skip + 1)
+}
@@ -0,0 +1,93 @@
+package treblle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorDetailsDisabledKeepsPayloadCompatible(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{})
+
+	errorProvider := NewErrorProvider()
+	errorProvider.AddError(fmt.Errorf("wrapped: %w", errors.New("root")), ServerError, "test")
+
+	errs := errorProvider.GetErrors()
+	require.Len(t, errs, 1)
+	assert.Empty(t, errs[0].Stack)
+	assert.Empty(t, errs[0].Chain)
+	assert.Empty(t, errs[0].ErrorClass)
+
+	payload, err := json.Marshal(errs[0])
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &fields))
+	assert.NotContains(t, fields, "stack")
+	assert.NotContains(t, fields, "chain")
+	assert.NotContains(t, fields, "error_class")
+}
+
+func TestErrorDetailsEnabled(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		ErrorDetailsEnabled:  true,
+		StackTraceDepth:      2,
+		StackTraceModulePath: "github.com/Treblle/treblle-go/v2",
+	})
+
+	_, statErr := os.Stat("/definitely/missing")
+	err := fmt.Errorf("load config: %w", errors.Join(statErr, errors.New("fallback failed")))
+
+	errorProvider := NewErrorProvider()
+	errorProvider.AddError(err, ServerError, "test")
+
+	errs := errorProvider.GetErrors()
+	require.Len(t, errs, 1)
+	info := errs[0]
+
+	assert.Equal(t, "*fmt.wrapError", info.ErrorClass)
+
+	require.Len(t, info.Chain, 4)
+	assert.Equal(t, "*errors.joinError", info.Chain[0].Type)
+	assert.Equal(t, "*fs.PathError", info.Chain[1].Type)
+	assert.Equal(t, "syscall.Errno", info.Chain[2].Type)
+	assert.Equal(t, "fallback failed", info.Chain[3].Message)
+
+	require.Len(t, info.Stack, 2)
+	assert.Equal(t, "TestErrorDetailsEnabled", info.Stack[0].Function)
+	// File paths only contain the module path when built with -trimpath
+	assert.True(t, strings.HasSuffix(info.Stack[0].File, "stacktrace_test.go"), info.Stack[0].File)
+	assert.Greater(t, info.Stack[0].Line, 0)
+
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+}
+
+func wrapAtOrigin() error {
+	return WrapError(errors.New("origin"))
+}
+
+func TestErrorDetailsUseWrapSite(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{ErrorDetailsEnabled: true})
+
+	errorProvider := NewErrorProvider()
+	errorProvider.AddError(wrapAtOrigin(), ServerError, "test")
+
+	errs := errorProvider.GetErrors()
+	require.Len(t, errs, 1)
+	require.NotEmpty(t, errs[0].Stack)
+	assert.Contains(t, errs[0].Stack[0].Function, "wrapAtOrigin")
+	assert.True(t, strings.HasSuffix(errs[0].Stack[0].File, "/stacktrace_test.go"), errs[0].Stack[0].File)
+}
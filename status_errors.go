@@ -0,0 +1,99 @@
+package treblle
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultStatusErrorTypes returns the status code mapping used when
+// Configuration.StatusErrorTypes is empty. Unmapped 5xx codes are always reported as ServerError.
+func DefaultStatusErrorTypes() map[int]ErrorType {
+	return map[int]ErrorType{
+		http.StatusBadRequest:          ValidationError,
+		http.StatusUnauthorized:        AuthenticationError,
+		http.StatusForbidden:           AuthorizationError,
+		http.StatusNotFound:            NotFoundError,
+		http.StatusUnprocessableEntity: ValidationError,
+		http.StatusTooManyRequests:     RateLimitError,
+	}
+}
+
+// addStatusError records a typed error for a failing response status, unless the request
+// already carries an error of that type or a recovered panic
+func addStatusError(errorProvider *ErrorProvider, code int, header http.Header, body []byte) {
+	if !Config.StatusErrorsEnabled {
+		return
+	}
+
+	errType, ok := statusErrorType(code)
+	if !ok {
+		return
+	}
+
+	for _, existing := range errorProvider.GetErrors() {
+		if existing.Type == errType || existing.Type == UnhandledExceptionError {
+			return
+		}
+	}
+
+	errorProvider.AddErrorInfo(ErrorInfo{
+		Message: statusErrorMessage(code, header, body),
+		Type:    errType,
+		Source:  "response_status",
+	})
+}
+
+// statusErrorType returns the error type configured for a status code
+func statusErrorType(code int) (ErrorType, bool) {
+	mapping := Config.StatusErrorTypes
+	if mapping == nil {
+		mapping = DefaultStatusErrorTypes()
+	}
+
+	if errType, ok := mapping[code]; ok {
+		return errType, true
+	}
+	if code >= 500 && code <= 599 {
+		return ServerError, true
+	}
+	return "", false
+}
+
+// statusErrorMessage extracts a message from an RFC 7807 problem document or a common
+// error/message field, falling back to the status text. Masked fields are never used, as the
+// message is sent unmasked.
+func statusErrorMessage(code int, header http.Header, body []byte) string {
+	fallback := strings.TrimSpace(fmt.Sprintf("%d %s", code, http.StatusText(code)))
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/problem+json" && !strings.HasSuffix(mediaType, "+json") {
+		return fallback
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return fallback
+	}
+
+	// RFC 7807 prefers detail over title
+	for _, field := range []string{"detail", "title", "message", "error_description", "error"} {
+		if shouldMaskField(field) {
+			continue
+		}
+		switch value := document[field].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case map[string]interface{}:
+			if message, ok := value["message"].(string); ok && message != "" && !shouldMaskField("message") {
+				return message
+			}
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,95 @@
+package treblle
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStatusError(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{StatusErrorsEnabled: true})
+
+	tests := []struct {
+		name        string
+		code        int
+		contentType string
+		body        string
+		errType     ErrorType
+		message     string
+	}{
+		{"problem-json", http.StatusForbidden, "application/problem+json", `{"type":"about:blank","title":"Forbidden","detail":"Missing scope users:write"}`, AuthorizationError, "Missing scope users:write"},
+		{"problem-json-title", http.StatusNotFound, "application/problem+json", `{"title":"User not found"}`, NotFoundError, "User not found"},
+		{"error-string", http.StatusUnauthorized, "application/json; charset=utf-8", `{"error":"token expired"}`, AuthenticationError, "token expired"},
+		{"error-object", http.StatusTooManyRequests, "application/json", `{"error":{"message":"slow down"}}`, RateLimitError, "slow down"},
+		{"message-field", http.StatusBadRequest, "application/json", `{"message":"name is required"}`, ValidationError, "name is required"},
+		{"non-json", http.StatusBadGateway, "text/html", `<html>bad gateway</html>`, ServerError, "502 Bad Gateway"},
+		{"unmapped-5xx", 599, "", ``, ServerError, "599"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorProvider := NewErrorProvider()
+			header := http.Header{"Content-Type": {tt.contentType}}
+			addStatusError(errorProvider, tt.code, header, []byte(tt.body))
+
+			errs := errorProvider.GetErrors()
+			require.Len(t, errs, 1)
+			assert.Equal(t, tt.errType, errs[0].Type)
+			assert.Equal(t, tt.message, errs[0].Message)
+			assert.Equal(t, "response_status", errs[0].Source)
+		})
+	}
+}
+
+func TestAddStatusErrorSkipsMaskedFields(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{StatusErrorsEnabled: true, AdditionalFieldsToMask: []string{"detail", "message"}})
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	for body, message := range map[string]string{
+		`{"detail":"card 4111111111111111 declined","title":"Payment failed"}`: "Payment failed",
+		`{"error":{"message":"token abc123 expired"}}`:                         "401 Unauthorized",
+	} {
+		errorProvider := NewErrorProvider()
+		addStatusError(errorProvider, http.StatusUnauthorized, header, []byte(body))
+		require.Len(t, errorProvider.GetErrors(), 1)
+		assert.Equal(t, message, errorProvider.GetErrors()[0].Message)
+	}
+}
+
+func TestAddStatusErrorSkips(t *testing.T) {
+	restoreConfig(t)
+	clearConfigEnv(t)
+	Configure(Configuration{
+		StatusErrorsEnabled: true,
+		StatusErrorTypes:    map[int]ErrorType{http.StatusConflict: ValidationError},
+	})
+
+	// Successful and unmapped 4xx responses carry no error
+	errorProvider := NewErrorProvider()
+	addStatusError(errorProvider, http.StatusOK, http.Header{}, nil)
+	addStatusError(errorProvider, http.StatusNotFound, http.Header{}, nil)
+	assert.Empty(t, errorProvider.GetErrors())
+
+	// Custom mapping
+	addStatusError(errorProvider, http.StatusConflict, http.Header{}, nil)
+	require.Len(t, errorProvider.GetErrors(), 1)
+	assert.Equal(t, ValidationError, errorProvider.GetErrors()[0].Type)
+
+	// A panic already explains the 500
+	errorProvider = NewErrorProvider()
+	errorProvider.AddCustomError("panic recovered: boom", UnhandledExceptionError, "middleware")
+	addStatusError(errorProvider, http.StatusInternalServerError, http.Header{}, nil)
+	assert.Len(t, errorProvider.GetErrors(), 1)
+
+	// Disabled by default
+	Configure(Configuration{})
+	errorProvider = NewErrorProvider()
+	addStatusError(errorProvider, http.StatusInternalServerError, http.Header{}, nil)
+	assert.Empty(t, errorProvider.GetErrors())
+}
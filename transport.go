@@ -0,0 +1,232 @@
+package treblle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+const (
+	// DirectionInbound marks requests received by the instrumented server
+	DirectionInbound = "inbound"
+	// DirectionOutbound marks requests made by the instrumented server to a third party
+	DirectionOutbound = "outbound"
+)
+
+// treblleInternalRequestKey marks requests sent to Treblle itself so they are never captured
+const treblleInternalRequestKey contextKey = "treblle_internal_request"
+
+// outboundTransport captures outgoing request/response pairs and ships them to Treblle
+type outboundTransport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps an http.RoundTripper so that every outgoing request made through it is
+// captured and sent to Treblle as an outbound event. If base is nil, http.DefaultTransport is used.
+//
+// Example:
+//
+//	client := &http.Client{Transport: treblle.Transport(nil)}
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &outboundTransport{base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if IsEnvironmentIgnored() || isInternalRequest(req) || !shouldCapture(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	errorProvider := NewErrorProvider()
+	startTime := time.Now()
+
+	body, req, err := readOutboundRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	requestInfo := getOutboundRequestInfo(req, body, errorProvider)
+
+	serverInfo := Config.serverInfo
+	serverInfo.Protocol = DetectProtocol(req)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		errorProvider.AddError(err, ServerError, "outbound_transport")
+		responseInfo := ResponseInfo{
+			Headers:  json.RawMessage("{}"),
+			Body:     json.RawMessage("{}"),
+			LoadTime: float64(time.Since(startTime).Microseconds()) / 1000.0,
+			Errors:   errorProvider.GetErrors(),
+		}
+		dispatchEvent(serverInfo, requestInfo, responseInfo, errorProvider)
+		return nil, err
+	}
+
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+
+	// Capture the body while the caller reads it and ship the event once it is done
+	resp.Body = &capturingBody{
+		ReadCloser: resp.Body,
+		onDone: func(captured []byte, size int) {
+			rec := httptest.NewRecorder()
+			for k, v := range resp.Header {
+				rec.Header()[k] = v
+			}
+			rec.Code = resp.StatusCode
+			rec.Body.Write(captured)
+
+			responseInfo := getResponseInfo(rec, startTime, errorProvider)
+			addStatusError(errorProvider, resp.StatusCode, resp.Header, captured)
+			if size <= maxResponseSize {
+				responseInfo.Size = size
+			}
+			responseInfo.Errors = errorProvider.GetErrors()
+
+			dispatchEvent(serverInfo, requestInfo, responseInfo, errorProvider)
+		},
+	}
+
+	return resp, nil
+}
+
+// isInternalRequest reports whether the request is one the SDK sends to Treblle
+func isInternalRequest(r *http.Request) bool {
+	internal, _ := r.Context().Value(treblleInternalRequestKey).(bool)
+	return internal
+}
+
+// readOutboundRequestBody returns a copy of the request body without consuming it for the
+// underlying transport. When the body has to be read, a clone of the request is returned.
+func readOutboundRequestBody(r *http.Request) ([]byte, *http.Request, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, r, nil
+	}
+
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err == nil {
+			defer rc.Close()
+			body, err := io.ReadAll(io.LimitReader(rc, maxResponseSize+1))
+			if err == nil {
+				return body, r, nil
+			}
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, r, fmt.Errorf("failed to read outbound request body: %w", err)
+	}
+
+	clone := r.Clone(r.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, clone, nil
+}
+
+// getOutboundRequestInfo builds request information for an outgoing client request
+func getOutboundRequestInfo(r *http.Request, body []byte, errorProvider *ErrorProvider) RequestInfo {
+	timestamp := time.Now().UTC().Format("2006-01-02 15:04:05")
+
+	// Client requests carry an absolute URL; strip the query and mask it separately
+	fullURL := *r.URL
+	fullURL.RawQuery = ""
+	fullURL.Fragment = ""
+	if fullURL.Host == "" {
+		fullURL.Host = r.Host
+	}
+
+	headerJSON, err := json.Marshal(maskHeaders(r.Header))
+	if err != nil {
+		headerJSON = json.RawMessage("{}")
+		errorProvider.AddError(err, MarshalError, "outbound_request_headers")
+	}
+
+	queryJSON := []byte("{}")
+	if queryParams := r.URL.Query(); len(queryParams) > 0 {
+		queryJSON = []byte(fmt.Sprintf("{%q: %q}", "query", getMaskedQueryString(queryParams)))
+	}
+
+	var bodyJSON json.RawMessage
+	if len(body) > 0 {
+		maskedBody, err := getMaskedJSON(body)
+		if err != nil {
+			bodyJSON = json.RawMessage("{}")
+		} else {
+			bodyJSON = maskedBody
+		}
+	}
+
+	// Ip is the client address; the instrumented server is the client here, so it is left empty
+	return RequestInfo{
+		Timestamp: timestamp,
+		Url:       fullURL.String(),
+		RoutePath: normalizeRoutePath(r.URL.Path),
+		UserAgent: r.UserAgent(),
+		Method:    r.Method,
+		Headers:   headerJSON,
+		Body:      bodyJSON,
+		Query:     queryJSON,
+		Direction: DirectionOutbound,
+	}
+}
+
+// capturingBody copies up to maxResponseSize+1 bytes of a response body as the caller reads it
+type capturingBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	size   int
+	once   sync.Once
+	onDone func(captured []byte, size int)
+}
+
+// Read implements io.Reader
+func (c *capturingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 {
+		c.size += n
+		if remaining := maxResponseSize + 1 - c.buf.Len(); remaining > 0 {
+			if remaining > n {
+				remaining = n
+			}
+			c.buf.Write(p[:remaining])
+		}
+	}
+	if err == io.EOF {
+		c.finish()
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (c *capturingBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.finish()
+	return err
+}
+
+// finish ships the captured exchange exactly once
+func (c *capturingBody) finish() {
+	c.once.Do(func() {
+		c.onDone(c.buf.Bytes(), c.size)
+	})
+}
+
+// withInternalRequest marks a context as belonging to a request sent to Treblle
+func withInternalRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, treblleInternalRequestKey, true)
+}
@@ -0,0 +1,137 @@
+package treblle
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportCapturesOutboundRequests(t *testing.T) {
+	received := make(chan MetaData, 1)
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil {
+			received <- meta
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer treblleServer.Close()
+	drainSends(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"username":"test","password":"secret123"}`, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":42,"api_key":"key123"}`))
+	}))
+	defer upstream.Close()
+
+	Configure(Configuration{
+		SDK_TOKEN:           "test-sdk-token",
+		API_KEY:             "test-api-key",
+		Endpoint:            treblleServer.URL,
+		DefaultFieldsToMask: []string{"password", "api_key", "authorization"},
+	})
+
+	client := &http.Client{Transport: Transport(nil)}
+	req, err := http.NewRequest(http.MethodPost, upstream.URL+"/users/123?api_key=abc", strings.NewReader(`{"username":"test","password":"secret123"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The caller still sees the unmasked, unconsumed body
+	assert.Equal(t, `{"id":42,"api_key":"key123"}`, string(body))
+
+	select {
+	case meta := <-received:
+		assert.Equal(t, DirectionOutbound, meta.Data.Request.Direction)
+		assert.Equal(t, "/users/{id}", meta.Data.Request.RoutePath)
+		assert.Equal(t, http.MethodPost, meta.Data.Request.Method)
+		assert.Equal(t, http.StatusCreated, meta.Data.Response.Code)
+		assert.Equal(t, len(body), meta.Data.Response.Size)
+		assert.JSONEq(t, `{"username":"test","password":"*********"}`, string(meta.Data.Request.Body))
+		assert.JSONEq(t, `{"id":42,"api_key":"*********"}`, string(meta.Data.Response.Body))
+		assert.Contains(t, string(meta.Data.Request.Headers), "Bearer *********")
+		assert.NotContains(t, string(meta.Data.Request.Query), "abc")
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for outbound event")
+	}
+}
+
+func TestTransportRecordsTransportErrors(t *testing.T) {
+	received := make(chan MetaData, 1)
+	treblleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta MetaData
+		if err := json.NewDecoder(r.Body).Decode(&meta); err == nil {
+			received <- meta
+		}
+	}))
+	defer treblleServer.Close()
+	drainSends(t)
+
+	Configure(Configuration{
+		SDK_TOKEN: "test-sdk-token",
+		API_KEY:   "test-api-key",
+		Endpoint:  treblleServer.URL,
+	})
+
+	client := &http.Client{Transport: Transport(nil)}
+	_, err := client.Get("http://127.0.0.1:1/unreachable")
+	require.Error(t, err)
+
+	select {
+	case meta := <-received:
+		require.Len(t, meta.Data.Response.Errors, 1)
+		assert.Equal(t, ServerError, meta.Data.Response.Errors[0].Type)
+		assert.Equal(t, "outbound_transport", meta.Data.Response.Errors[0].Source)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for outbound event")
+	}
+}
+
+func TestTransportSkipsIgnoredRoutes(t *testing.T) {
+	restoreConfig(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	var events []MetaData
+	Configure(Configuration{
+		SDK_TOKEN:      "test-sdk-token",
+		IgnoredRoutes:  []string{"/health"},
+		DisableTreblle: true,
+		Exporters: []Exporter{ExporterFunc(func(event MetaData) error {
+			events = append(events, event)
+			return nil
+		})},
+	})
+
+	client := &http.Client{Transport: Transport(nil)}
+	for _, path := range []string{"/health", "/users"} {
+		resp, err := client.Get(upstream.URL + path)
+		require.NoError(t, err)
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	syncSends.wait()
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "/users", events[0].Data.Request.RoutePath)
+	assert.Empty(t, events[0].Data.Request.Ip, "the remote host is not the client address")
+}
@@ -14,43 +14,62 @@ const (
 	timeoutDuration = 2 * time.Second
 )
 
+// treblleClient sends events to Treblle; its idle connections are closed by Shutdown
+var treblleClient = &http.Client{
+	// No need for timeout here as we're using context timeout
+}
+
 type BaseUrlOptions struct {
 	Debug bool
 }
 
-func getTreblleBaseUrl() string {
-	// If custom endpoint is set, use it
+// defaultEndpoints are the Treblle ingest endpoints used when no Endpoint is configured
+var defaultEndpoints = []string{
+	"https://rocknrolla.treblle.com",
+	"https://punisher.treblle.com",
+	"https://sicario.treblle.com",
+}
+
+// Endpoints returns the configured endpoint, or the default Treblle endpoints events are load
+// balanced across
+func Endpoints() []string {
 	if Config.Endpoint != "" {
-		return Config.Endpoint
+		return []string{Config.Endpoint}
 	}
+	return append([]string(nil), defaultEndpoints...)
+}
+
+func getTreblleBaseUrl() string {
+	return treblleBaseUrl(Config.Endpoint)
+}
 
-	// Default Treblle endpoints
-	treblleBaseUrls := []string{
-		"https://rocknrolla.treblle.com",
-		"https://punisher.treblle.com",
-		"https://sicario.treblle.com",
+// treblleBaseUrl returns endpoint, or one of the default endpoints if it is empty
+func treblleBaseUrl(endpoint string) string {
+	// If custom endpoint is set, use it
+	if endpoint != "" {
+		return endpoint
 	}
 
 	rand.Seed(time.Now().Unix())
-	randomUrlIndex := rand.Intn(len(treblleBaseUrls))
+	randomUrlIndex := rand.Intn(len(defaultEndpoints))
 
-	return treblleBaseUrls[randomUrlIndex]
+	return defaultEndpoints[randomUrlIndex]
 }
 
-func sendToTreblle(treblleInfo MetaData) {
+func sendToTreblle(treblleInfo MetaData, target eventTarget) {
 	// Use the context-aware version with a default timeout
 	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
 	defer cancel()
 
-	sendToTreblleWithContext(ctx, treblleInfo)
+	deliver(ctx, treblleInfo, target)
 }
 
-// sendToTreblleWithContext sends data to Treblle with context support
-func sendToTreblleWithContext(ctx context.Context, treblleInfo MetaData) error {
-	baseUrl := getTreblleBaseUrl()
+// sendToTreblleWithContext sends data to Treblle, at endpoint if it is set, with context support
+func sendToTreblleWithContext(ctx context.Context, treblleInfo MetaData, endpoint string) error {
+	baseUrl := treblleBaseUrl(endpoint)
 
 	// Print debug info if debug mode is enabled
-	if Config.Debug {
+	if debugEnabled() {
 		fmt.Printf("\n==== DEBUG: TREBLLE ENDPOINT ====\n")
 		fmt.Printf("Sending to: %s\n", baseUrl)
 		fmt.Printf("================================\n")
@@ -62,7 +81,7 @@ func sendToTreblleWithContext(ctx context.Context, treblleInfo MetaData) error {
 	}
 
 	// Print debug info if debug mode is enabled
-	if Config.Debug {
+	if debugEnabled() {
 		prettyJson, _ := json.MarshalIndent(treblleInfo, "", "  ")
 		fmt.Println("\n==== DEBUG: TREBLLE PAYLOAD ====")
 		fmt.Println(string(prettyJson))
@@ -76,24 +95,22 @@ func sendToTreblleWithContext(ctx context.Context, treblleInfo MetaData) error {
 		fmt.Println("=================================")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseUrl, bytes.NewBuffer(bytesRepresentation))
+	// Mark the request as internal so an instrumented Transport never captures it
+	req, err := http.NewRequestWithContext(withInternalRequest(ctx), http.MethodPost, baseUrl, bytes.NewBuffer(bytesRepresentation))
 	if err != nil {
 		return err
 	}
 	// Set the content type from the writer, it includes necessary boundary as well
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", Config.APIKey)
+	req.Header.Set("x-api-key", treblleInfo.ApiKey)
 
-	client := &http.Client{
-		// No need for timeout here as we're using context timeout
-	}
-	resp, err := client.Do(req)
+	resp, err := treblleClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if Config.Debug {
+	if debugEnabled() {
 		fmt.Printf("\n==== DEBUG: TREBLLE RESPONSE ====\n")
 		fmt.Printf("Status: %s\n", resp.Status)
 
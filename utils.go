@@ -3,6 +3,7 @@ package treblle
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 )
@@ -30,15 +31,59 @@ func getMaskedQueryString(query url.Values) string {
 	return maskedQuery.Encode()
 }
 
+// maskHeaders flattens single-value headers and masks sensitive ones
+func maskHeaders(header http.Header) map[string]interface{} {
+	return maskHeadersReport(header, nil)
+}
+
+// maskHeadersReport masks headers like maskHeaders and records every masked header in report
+func maskHeadersReport(header http.Header, report *maskReport) map[string]interface{} {
+	headers := make(map[string]interface{})
+	for key, values := range header {
+		if len(values) == 0 {
+			continue
+		}
+
+		// For multiple values, keep them as an array
+		if len(values) > 1 {
+			// If the field should be masked, mask each value
+			if shouldMaskField(key) {
+				report.add(key, key)
+				maskedValues := make([]interface{}, len(values))
+				for i := range values {
+					maskedValues[i] = maskValue(values[i], key)
+				}
+				headers[key] = maskedValues
+			} else {
+				headers[key] = values
+			}
+		} else {
+			// Single value
+			if shouldMaskField(key) {
+				report.add(key, key)
+				headers[key] = maskValue(values[0], key)
+			} else {
+				headers[key] = values[0]
+			}
+		}
+	}
+	return headers
+}
+
 // getMaskedJSON masks sensitive fields in JSON data
 func getMaskedJSON(data []byte) (json.RawMessage, error) {
+	return maskJSONReport(data, nil)
+}
+
+// maskJSONReport masks JSON data like getMaskedJSON and records every masked path in report
+func maskJSONReport(data []byte, report *maskReport) (json.RawMessage, error) {
 	var jsonData interface{}
 	if err := json.Unmarshal(data, &jsonData); err != nil {
 		// Return the original error from json.Unmarshal
 		return nil, err
 	}
 
-	maskedData := maskData(jsonData)
+	maskedData := maskData(jsonData, "", report)
 	maskedJSON, err := json.Marshal(maskedData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal masked data: %v", err)
@@ -48,11 +93,12 @@ func getMaskedJSON(data []byte) (json.RawMessage, error) {
 }
 
 // maskMap masks sensitive fields in a map based on configuration
-func maskMap(data map[string]interface{}) map[string]interface{} {
+func maskMap(data map[string]interface{}, path string, report *maskReport) map[string]interface{} {
 	result := make(map[string]interface{})
 	for key, value := range data {
 		// Check if this key should be masked
 		if shouldMaskField(strings.ToLower(key)) {
+			report.add(joinMaskPath(path, key), key)
 			switch v := value.(type) {
 			case string:
 				result[key] = maskValue(v, key)
@@ -74,7 +120,7 @@ func maskMap(data map[string]interface{}) map[string]interface{} {
 				}
 			}
 		} else {
-			result[key] = maskData(value)
+			result[key] = maskData(value, joinMaskPath(path, key), report)
 		}
 	}
 	return result
@@ -126,43 +172,61 @@ func maskValue(value interface{}, key string) interface{} {
 }
 
 // maskData recursively masks data in different formats
-func maskData(data interface{}) interface{} {
+func maskData(data interface{}, path string, report *maskReport) interface{} {
 	switch v := data.(type) {
 	case map[string]interface{}:
-		return maskMap(v)
+		return maskMap(v, path, report)
 	case []interface{}:
-		return maskArray(v)
+		return maskArray(v, path, report)
 	default:
 		return v
 	}
 }
 
 // maskArray handles masking of JSON arrays
-func maskArray(data []interface{}) []interface{} {
+func maskArray(data []interface{}, path string, report *maskReport) []interface{} {
 	result := make([]interface{}, len(data))
 	for i, v := range data {
-		result[i] = maskData(v)
+		result[i] = maskData(v, fmt.Sprintf("%s[%d]", path, i), report)
 	}
 	return result
 }
 
 // shouldMaskField checks if a field should be masked based on configuration
 func shouldMaskField(fieldName string) bool {
+	_, ok := matchMaskedField(fieldName)
+	return ok
+}
+
+// matchMaskedField returns the configured field that causes fieldName to be masked
+func matchMaskedField(fieldName string) (string, bool) {
 	// Convert field name to lowercase for consistent matching
 	fieldName = strings.ToLower(fieldName)
 
 	// Check direct match
-	if _, exists := Config.FieldsMap[fieldName]; exists {
-		return true
+	fieldsMap := currentSettings().fieldsMap
+	if _, exists := fieldsMap[fieldName]; exists {
+		return fieldName, true
 	}
 
 	// Check with common prefixes
 	prefixes := []string{"x-", "x_"}
 	for _, prefix := range prefixes {
-		if _, exists := Config.FieldsMap[prefix+fieldName]; exists {
-			return true
+		if _, exists := fieldsMap[prefix+fieldName]; exists {
+			return prefix + fieldName, true
 		}
 	}
 
+	return "", false
+}
+
+// pathMatchesAny checks if a request path equals one of the given paths, ignoring trailing slashes
+func pathMatchesAny(path string, paths []string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, candidate := range paths {
+		if path == strings.TrimSuffix(strings.TrimSpace(candidate), "/") {
+			return true
+		}
+	}
 	return false
 }